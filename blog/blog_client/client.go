@@ -6,11 +6,17 @@ import (
 	"github.com/wiliamhw/golang-grpc-example/blog/blogpb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"io"
 	"log"
+	"time"
 )
 
-const serverPort = 50051
+const (
+	serverPort = 50051
+	authToken  = "stephane-secret-token" // see blog/tokens.json
+	otherToken = "john-secret-token"
+)
 
 func main() {
 	fmt.Println("Blog Client")
@@ -33,22 +39,33 @@ func main() {
 	readBlog(c, "1dfsoijfs")
 	readBlog(c, blog.GetId())
 	updateBlog(c, blog.GetId())
+	deleteBlogAs(c, blog.GetId(), otherToken) // should be denied
 	deleteBlog(c, blog.GetId())
 	listBlog(c)
 }
 
 func createBlog(c blogpb.BlogServiceClient) *blogpb.Blog {
 	fmt.Println("\nCreating the blog")
+	// The author is taken from the token, not from the blog
 	blog := &blogpb.Blog{
-		AuthorId: "Stephane",
-		Title:    "My First Blog",
-		Content:  "Content of the first blog",
+		Title:   "My First Blog",
+		Content: "Content of the first blog",
 	}
-	res, err := c.CreateBlog(context.Background(), &blogpb.CreateBlogRequest{Blog: blog})
+	req := &blogpb.CreateBlogRequest{Blog: blog}
+
+	// Retrying with the same idempotency key must not create a second blog
+	ctx := metadata.AppendToOutgoingContext(withToken(authToken), "idempotency-key", fmt.Sprintf("create-%d", time.Now().UnixNano()))
+	res, err := c.CreateBlog(ctx, req)
 	if err != nil {
 		log.Fatalf("Unexpected error: %v", err)
 	}
 	fmt.Printf("Blog has been created: %v\n", res)
+
+	retry, err := c.CreateBlog(ctx, req)
+	if err != nil {
+		log.Fatalf("Unexpected error on retry: %v", err)
+	}
+	fmt.Printf("Retry returned the same blog: %v\n", retry.GetBlog().GetId() == res.GetBlog().GetId())
 	return res.GetBlog()
 }
 
@@ -69,12 +86,11 @@ func readBlog(c blogpb.BlogServiceClient, blogId string) {
 func updateBlog(c blogpb.BlogServiceClient, blogId string) *blogpb.Blog {
 	fmt.Println("\nUpdating the blog")
 	newBlog := &blogpb.Blog{
-		Id:       blogId,
-		AuthorId: "ChangeAuthor",
-		Title:    "title-test",
-		Content:  "content-test",
+		Id:      blogId,
+		Title:   "title-test",
+		Content: "content-test",
 	}
-	res, err := c.UpdateBlog(context.Background(), &blogpb.UpdateBlogRequest{Blog: newBlog})
+	res, err := c.UpdateBlog(withToken(authToken), &blogpb.UpdateBlogRequest{Blog: newBlog})
 	if err != nil {
 		log.Fatalf("Unexpected error: %v", err)
 	}
@@ -83,13 +99,17 @@ func updateBlog(c blogpb.BlogServiceClient, blogId string) *blogpb.Blog {
 }
 
 func deleteBlog(c blogpb.BlogServiceClient, blogId string) {
+	deleteBlogAs(c, blogId, authToken)
+}
+
+func deleteBlogAs(c blogpb.BlogServiceClient, blogId string, token string) {
 	fmt.Printf("\nDeleting the blog with id: %v\n", blogId)
 
 	req := &blogpb.DeleteBlogRequest{
 		BlogId: blogId,
 	}
 
-	res, err := c.DeleteBlog(context.Background(), req)
+	res, err := c.DeleteBlog(withToken(token), req)
 	if err != nil {
 		fmt.Printf("Error happened while deleting: %v\n", err)
 	}
@@ -114,3 +134,8 @@ func listBlog(c blogpb.BlogServiceClient) {
 		fmt.Printf("Response from ListBlog: %v", res.GetBlog())
 	}
 }
+
+// withToken attaches the bearer token the server uses to identify the caller.
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"os"
+	"strings"
+)
+
+const (
+	authorizationHeader = "authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// identity is the authenticated caller of an RPC.
+type identity struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+func (id *identity) hasRole(role string) bool {
+	if id == nil {
+		return false
+	}
+	for _, r := range id.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenStore maps bearer tokens to the identity they authenticate.
+type tokenStore map[string]*identity
+
+// loadTokens reads a JSON object of the form {"<token>": {"user_id": "...", "roles": [...]}}.
+func loadTokens(path string) (tokenStore, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens := tokenStore{}
+	if err := json.Unmarshal(raw, &tokens); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %v", path, err)
+	}
+	for token, id := range tokens {
+		if id == nil || id.UserID == "" {
+			return nil, fmt.Errorf("token %q in %s has no user_id", token, path)
+		}
+	}
+	return tokens, nil
+}
+
+// authenticate resolves the caller from the "authorization" metadata header.
+// A request without the header is anonymous and yields a nil identity.
+func (ts tokenStore) authenticate(ctx context.Context) (*identity, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	values := md.Get(authorizationHeader)
+	if len(values) == 0 {
+		return nil, nil
+	}
+	if !strings.HasPrefix(values[0], bearerPrefix) {
+		return nil, status.Errorf(codes.Unauthenticated, "Authorization header must use the Bearer scheme")
+	}
+	id, ok := ts[strings.TrimPrefix(values[0], bearerPrefix)]
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "Invalid bearer token")
+	}
+	return id, nil
+}
+
+type identityKey struct{}
+
+func identityFromContext(ctx context.Context) *identity {
+	id, _ := ctx.Value(identityKey{}).(*identity)
+	return id
+}
+
+func (ts tokenStore) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	id, err := ts.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, identityKey{}, id), req)
+}
+
+func (ts tokenStore) streamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	id, err := ts.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &identityStream{
+		ServerStream: stream,
+		ctx:          context.WithValue(stream.Context(), identityKey{}, id),
+	})
+}
+
+// identityStream overrides the context of a server stream so handlers can see the caller.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"expvar"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"time"
+)
+
+var (
+	cacheHits   = expvar.NewInt("blog_cache_hits")
+	cacheMisses = expvar.NewInt("blog_cache_misses")
+)
+
+// lruCache is a size and TTL bounded cache of blog items keyed by id.
+type lruCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is the most recently used entry
+	entries map[primitive.ObjectID]*list.Element
+}
+
+type cacheEntry struct {
+	id        primitive.ObjectID
+	data      blogItem
+	expiresAt time.Time
+}
+
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: map[primitive.ObjectID]*list.Element{},
+	}
+}
+
+func (c *lruCache) get(id primitive.ObjectID) (*blogItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, id)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	data := entry.data
+	return &data, true
+}
+
+func (c *lruCache) put(data *blogItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{id: data.ID, data: *data, expiresAt: time.Now().Add(c.ttl)}
+	if el, ok := c.entries[data.ID]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[data.ID] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).id)
+	}
+}
+
+func (c *lruCache) remove(id primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[id]; ok {
+		c.order.Remove(el)
+		delete(c.entries, id)
+	}
+}
+
+// cachedBlogStore is a read-through cache in front of another blogStore.
+// Concurrent misses for the same blog share a single load.
+type cachedBlogStore struct {
+	blogStore
+	cache *lruCache
+	group singleflight.Group
+
+	// epoch is bumped on every invalidation so loads that started before a
+	// write do not put stale data back into the cache.
+	mu    sync.Mutex
+	epoch uint64
+}
+
+func newCachedBlogStore(store blogStore, cache *lruCache) *cachedBlogStore {
+	return &cachedBlogStore{blogStore: store, cache: cache}
+}
+
+func (s *cachedBlogStore) Get(ctx context.Context, id primitive.ObjectID) (*blogItem, error) {
+	if data, ok := s.cache.get(id); ok {
+		cacheHits.Add(1)
+		return data, nil
+	}
+	cacheMisses.Add(1)
+
+	v, err, _ := s.group.Do(id.Hex(), func() (interface{}, error) {
+		s.mu.Lock()
+		epoch := s.epoch
+		s.mu.Unlock()
+
+		// The load is shared between callers, so it must not be cut short by one of them going away.
+		data, err := s.blogStore.Get(context.Background(), id)
+		if err != nil {
+			return nil, err
+		}
+
+		s.mu.Lock()
+		if epoch == s.epoch {
+			s.cache.put(data)
+		}
+		s.mu.Unlock()
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	data := *v.(*blogItem)
+	return &data, nil
+}
+
+func (s *cachedBlogStore) Replace(ctx context.Context, data *blogItem) error {
+	defer s.invalidate(data.ID)
+	return s.blogStore.Replace(ctx, data)
+}
+
+func (s *cachedBlogStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	defer s.invalidate(id)
+	return s.blogStore.Delete(ctx, id)
+}
+
+func (s *cachedBlogStore) invalidate(id primitive.ObjectID) {
+	s.mu.Lock()
+	s.epoch++
+	s.cache.remove(id)
+	s.mu.Unlock()
+	s.group.Forget(id.Hex())
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"log"
+	"time"
+)
+
+const (
+	idempotencyKeyHeader  = "idempotency-key"
+	idempotencyTTLIndex   = "created_at_ttl"
+	defaultIdempotencyTTL = 24 * time.Hour
+	// idempotencyLease is how long a request may run before a retry presumes
+	// it lost, e.g. to a crash, and runs it again.
+	idempotencyLease = 30 * time.Second
+)
+
+var (
+	errIdempotencyKeyExists = errors.New("idempotency key exists")
+	errIdempotencyLeaseLost = errors.New("idempotency key was taken over by a retry")
+)
+
+// idempotencyRecord is stored once per key. It is inserted before the handler
+// runs so concurrent retries see the key as in progress, and completed with the
+// serialized response afterwards. StartedAt identifies the request that owns
+// the record, a retry taking over a stale record replaces it.
+type idempotencyRecord struct {
+	ID           string    `bson:"_id"`
+	RequestHash  []byte    `bson:"request_hash"`
+	Completed    bool      `bson:"completed"`
+	ResponseType string    `bson:"response_type,omitempty"`
+	Response     []byte    `bson:"response,omitempty"`
+	StartedAt    time.Time `bson:"started_at"`
+	CreatedAt    time.Time `bson:"created_at"`
+}
+
+// idempotencyRecords persists idempotency records. The owner of a record is
+// the request that set its StartedAt, updates made with another startedAt
+// fail with errIdempotencyLeaseLost, or do nothing for Release.
+type idempotencyRecords interface {
+	// Insert fails with errIdempotencyKeyExists when the id is taken.
+	Insert(ctx context.Context, record *idempotencyRecord) error
+	Get(ctx context.Context, id string) (*idempotencyRecord, error)
+	// TakeOver moves an in-progress record started at previous to startedAt,
+	// and reports whether it did.
+	TakeOver(ctx context.Context, id string, previous, startedAt time.Time) (bool, error)
+	Complete(ctx context.Context, id string, startedAt time.Time, responseType string, response []byte) error
+	Release(ctx context.Context, id string, startedAt time.Time) error
+}
+
+// requestIDGetter is implemented by requests that carry a request_id field.
+type requestIDGetter interface {
+	GetRequestId() string
+}
+
+type idempotencyStore struct {
+	records idempotencyRecords
+	methods map[string]bool
+	lease   time.Duration
+}
+
+func newIdempotencyStore(records idempotencyRecords, methods ...string) *idempotencyStore {
+	store := &idempotencyStore{records: records, methods: map[string]bool{}, lease: idempotencyLease}
+	for _, m := range methods {
+		store.methods[m] = true
+	}
+	return store
+}
+
+// mongoIdempotencyRecords keeps idempotency records in a MongoDB collection.
+type mongoIdempotencyRecords struct {
+	collection *mongo.Collection
+}
+
+// ensureIndexes lets MongoDB expire records once they are older than ttl. An
+// index created with another ttl is updated in place, because creating it again
+// with different options fails.
+func (r *mongoIdempotencyRecords) ensureIndexes(ctx context.Context, ttl time.Duration) error {
+	seconds := int32(ttl.Seconds())
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName(idempotencyTTLIndex).SetExpireAfterSeconds(seconds),
+	})
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.Name != "IndexOptionsConflict" {
+		return err
+	}
+	return r.collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: r.collection.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "name", Value: idempotencyTTLIndex},
+			{Key: "expireAfterSeconds", Value: seconds},
+		}},
+	}).Err()
+}
+
+func (r *mongoIdempotencyRecords) Insert(ctx context.Context, record *idempotencyRecord) error {
+	_, err := r.collection.InsertOne(ctx, record)
+	if mongo.IsDuplicateKeyError(err) {
+		return errIdempotencyKeyExists
+	}
+	return err
+}
+
+func (r *mongoIdempotencyRecords) Get(ctx context.Context, id string) (*idempotencyRecord, error) {
+	record := &idempotencyRecord{}
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *mongoIdempotencyRecords) TakeOver(ctx context.Context, id string, previous, startedAt time.Time) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "completed": false, "started_at": previous},
+		bson.M{"$set": bson.M{"started_at": startedAt}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount == 1, nil
+}
+
+func (r *mongoIdempotencyRecords) Complete(
+	ctx context.Context,
+	id string,
+	startedAt time.Time,
+	responseType string,
+	response []byte,
+) error {
+	res, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "started_at": startedAt}, bson.M{"$set": bson.M{
+		"completed":     true,
+		"response_type": responseType,
+		"response":      response,
+	}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errIdempotencyLeaseLost
+	}
+	return nil
+}
+
+func (r *mongoIdempotencyRecords) Release(ctx context.Context, id string, startedAt time.Time) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "started_at": startedAt})
+	return err
+}
+
+// idempotencyKey reads the key from the metadata header, falling back to the request_id field.
+func idempotencyKey(ctx context.Context, req interface{}) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(idempotencyKeyHeader); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	if r, ok := req.(requestIDGetter); ok {
+		return r.GetRequestId()
+	}
+	return ""
+}
+
+func (s *idempotencyStore) unaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	key := idempotencyKey(ctx, req)
+	msg, ok := req.(proto.Message)
+	if !s.methods[info.FullMethod] || key == "" || !ok {
+		return handler(ctx, req)
+	}
+
+	payload, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot hash request: %v", err)
+	}
+	requestHash := sha256.Sum256(payload)
+
+	// Keys are scoped to the method and the caller so they cannot collide across users.
+	var callerID string
+	if caller := identityFromContext(ctx); caller != nil {
+		callerID = caller.UserID
+	}
+	scope := sha256.Sum256([]byte(info.FullMethod + "\x00" + callerID + "\x00" + key))
+	id := hex.EncodeToString(scope[:])
+
+	// MongoDB keeps milliseconds, so does the owner stamp
+	startedAt := time.Now().Truncate(time.Millisecond)
+	err = s.records.Insert(context.Background(), &idempotencyRecord{
+		ID:          id,
+		RequestHash: requestHash[:],
+		StartedAt:   startedAt,
+		CreatedAt:   startedAt,
+	})
+	if err == errIdempotencyKeyExists {
+		res, takenOver, err := s.replay(id, requestHash[:], startedAt)
+		if !takenOver {
+			return res, err
+		}
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Cannot record idempotency key: %v", err)
+	}
+
+	res, err := handler(ctx, req)
+	if err != nil {
+		// Release the key so the client can retry a failed request.
+		s.release(id, startedAt)
+		return nil, err
+	}
+
+	if err := s.complete(id, startedAt, res); err != nil {
+		// Without a stored response retries could only be rejected as in progress,
+		// so they run again instead.
+		log.Printf("Cannot store response for idempotency key: %v\n", err)
+		s.release(id, startedAt)
+	}
+	return res, nil
+}
+
+func (s *idempotencyStore) release(id string, startedAt time.Time) {
+	if err := s.records.Release(context.Background(), id, startedAt); err != nil {
+		log.Printf("Cannot release idempotency key: %v\n", err)
+	}
+}
+
+func (s *idempotencyStore) complete(id string, startedAt time.Time, res interface{}) error {
+	msg, ok := res.(proto.Message)
+	if !ok {
+		return fmt.Errorf("response %T is not a proto message", res)
+	}
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	responseType := string(msg.ProtoReflect().Descriptor().FullName())
+	return s.records.Complete(context.Background(), id, startedAt, responseType, raw)
+}
+
+// replay returns the stored response of a previous request with the same key.
+// A previous request still in progress after the lease is presumed lost, replay
+// then takes its record over as startedAt and reports it, so the caller runs
+// the request again.
+func (s *idempotencyStore) replay(id string, requestHash []byte, startedAt time.Time) (interface{}, bool, error) {
+	record, err := s.records.Get(context.Background(), id)
+	if err != nil {
+		return nil, false, status.Errorf(codes.Aborted, "Idempotency key is being released, retry the request: %v", err)
+	}
+	if !bytes.Equal(record.RequestHash, requestHash) {
+		return nil, false, status.Errorf(
+			codes.InvalidArgument,
+			"Idempotency key was already used with a different request",
+		)
+	}
+	if !record.Completed {
+		if startedAt.Sub(record.StartedAt) < s.lease {
+			return nil, false, status.Errorf(codes.Aborted, "A request with this idempotency key is still in progress")
+		}
+		takenOver, err := s.records.TakeOver(context.Background(), id, record.StartedAt, startedAt)
+		if err != nil {
+			return nil, false, status.Errorf(codes.Internal, "Cannot take over idempotency key: %v", err)
+		}
+		if !takenOver {
+			// Another retry was faster, or the request completed meanwhile
+			return nil, false, status.Errorf(codes.Aborted, "A request with this idempotency key is still in progress")
+		}
+		log.Printf("Idempotency key started at %v was not completed, running the request again\n", record.StartedAt)
+		return nil, true, nil
+	}
+
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(record.ResponseType))
+	if err != nil {
+		return nil, false, status.Errorf(codes.Internal, "Unknown stored response type: %v", err)
+	}
+	res := mt.New().Interface()
+	if err := proto.Unmarshal(record.Response, res); err != nil {
+		return nil, false, status.Errorf(codes.Internal, "Cannot decode stored response: %v", err)
+	}
+	return res, false, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"github.com/wiliamhw/golang-grpc-example/blog/blogpb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryIdempotencyRecords keeps records in memory, updating them with the
+// same ownership rules as mongoIdempotencyRecords.
+type memoryIdempotencyRecords struct {
+	mu      sync.Mutex
+	records map[string]idempotencyRecord
+}
+
+func newMemoryIdempotencyRecords() *memoryIdempotencyRecords {
+	return &memoryIdempotencyRecords{records: map[string]idempotencyRecord{}}
+}
+
+func (r *memoryIdempotencyRecords) Insert(_ context.Context, record *idempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.records[record.ID]; ok {
+		return errIdempotencyKeyExists
+	}
+	r.records[record.ID] = *record
+	return nil
+}
+
+func (r *memoryIdempotencyRecords) Get(_ context.Context, id string) (*idempotencyRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok {
+		return nil, mongo.ErrNoDocuments
+	}
+	return &record, nil
+}
+
+func (r *memoryIdempotencyRecords) TakeOver(_ context.Context, id string, previous, startedAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || record.Completed || !record.StartedAt.Equal(previous) {
+		return false, nil
+	}
+	record.StartedAt = startedAt
+	r.records[id] = record
+	return true, nil
+}
+
+func (r *memoryIdempotencyRecords) Complete(
+	_ context.Context,
+	id string,
+	startedAt time.Time,
+	responseType string,
+	response []byte,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || !record.StartedAt.Equal(startedAt) {
+		return errIdempotencyLeaseLost
+	}
+	record.Completed = true
+	record.ResponseType = responseType
+	record.Response = response
+	r.records[id] = record
+	return nil
+}
+
+func (r *memoryIdempotencyRecords) Release(_ context.Context, id string, startedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if record, ok := r.records[id]; ok && record.StartedAt.Equal(startedAt) {
+		delete(r.records, id)
+	}
+	return nil
+}
+
+var deleteBlogInfo = &grpc.UnaryServerInfo{FullMethod: "/blog.BlogService/DeleteBlog"}
+
+// countingHandler answers DeleteBlog requests with their blog id, counting calls.
+func countingHandler(calls *int) grpc.UnaryHandler {
+	return func(_ context.Context, req interface{}) (interface{}, error) {
+		*calls++
+		return &blogpb.DeleteBlogResponse{BlogId: req.(*blogpb.DeleteBlogRequest).GetBlogId()}, nil
+	}
+}
+
+func callWithKey(s *idempotencyStore, key string, req *blogpb.DeleteBlogRequest, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(idempotencyKeyHeader, key))
+	return s.unaryInterceptor(ctx, req, deleteBlogInfo, handler)
+}
+
+func TestIdempotencyReplaysSamePayload(t *testing.T) {
+	s := newIdempotencyStore(newMemoryIdempotencyRecords(), deleteBlogInfo.FullMethod)
+	calls := 0
+	req := &blogpb.DeleteBlogRequest{BlogId: "42"}
+
+	first, err := callWithKey(s, "key-1", req, countingHandler(&calls))
+	if err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	second, err := callWithKey(s, "key-1", req, countingHandler(&calls))
+	if err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if !proto.Equal(first.(proto.Message), second.(proto.Message)) {
+		t.Errorf("retry returned %v, want the stored %v", second, first)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+
+	// Another key runs the handler again
+	if _, err := callWithKey(s, "key-2", req, countingHandler(&calls)); err != nil || calls != 2 {
+		t.Errorf("call with another key = %v after %d handler calls, want a second call", err, calls)
+	}
+}
+
+func TestIdempotencyRejectsDifferentPayload(t *testing.T) {
+	s := newIdempotencyStore(newMemoryIdempotencyRecords(), deleteBlogInfo.FullMethod)
+	calls := 0
+	if _, err := callWithKey(s, "key", &blogpb.DeleteBlogRequest{BlogId: "42"}, countingHandler(&calls)); err != nil {
+		t.Fatalf("first call failed: %v", err)
+	}
+	_, err := callWithKey(s, "key", &blogpb.DeleteBlogRequest{BlogId: "43"}, countingHandler(&calls))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("reusing the key for another request = %v, want %v", err, codes.InvalidArgument)
+	}
+	if calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestIdempotencyReleasesFailedRequests(t *testing.T) {
+	s := newIdempotencyStore(newMemoryIdempotencyRecords(), deleteBlogInfo.FullMethod)
+	req := &blogpb.DeleteBlogRequest{BlogId: "42"}
+	failing := func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	if _, err := callWithKey(s, "key", req, failing); status.Code(err) != codes.Unavailable {
+		t.Fatalf("failing call = %v, want %v", err, codes.Unavailable)
+	}
+	calls := 0
+	if _, err := callWithKey(s, "key", req, countingHandler(&calls)); err != nil || calls != 1 {
+		t.Errorf("retry after a failure = %v after %d handler calls, want the handler to run", err, calls)
+	}
+}
+
+func TestIdempotencyTakesOverStaleRequests(t *testing.T) {
+	s := newIdempotencyStore(newMemoryIdempotencyRecords(), deleteBlogInfo.FullMethod)
+	s.lease = 10 * time.Millisecond
+	req := &blogpb.DeleteBlogRequest{BlogId: "42"}
+
+	// The first request outlives its lease, as if the server crashed while running it
+	calls := 0
+	_, _ = callWithKey(s, "key", req, func(context.Context, interface{}) (interface{}, error) {
+		if _, err := callWithKey(s, "key", req, countingHandler(&calls)); status.Code(err) != codes.Aborted {
+			t.Errorf("retry within the lease = %v, want %v", err, codes.Aborted)
+		}
+
+		time.Sleep(2 * s.lease)
+		res, err := callWithKey(s, "key", req, countingHandler(&calls))
+		if err != nil || calls != 1 {
+			t.Errorf("retry after the lease = %v, %v after %d handler calls, want the handler to run", res, err, calls)
+		}
+		// Finishing late does not replace the response of the retry
+		return &blogpb.DeleteBlogResponse{BlogId: "stale"}, nil
+	})
+
+	res, err := callWithKey(s, "key", req, countingHandler(&calls))
+	if err != nil || res.(*blogpb.DeleteBlogResponse).GetBlogId() != "42" || calls != 1 {
+		t.Errorf("replay = %v, %v after %d handler calls, want the response of the retry", res, err, calls)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+const (
+	migrationsCollection  = "schema_migrations"
+	blogCollection        = "blog"
+	idempotencyCollection = "idempotency_keys"
+)
+
+// migration upgrades the database by one schema version. Up must be safe to
+// run again if the process dies before the migration is recorded.
+type migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+type migrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// migrations must be kept sorted by version, append new ones at the end.
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "index blogs by author",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(blogCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "author_id", Value: 1}},
+				Options: options.Index().SetName("author_id"),
+			})
+			return err
+		},
+	},
+	{
+		Version:     2,
+		Description: "backfill created_at and updated_at from the blog id",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// The ObjectID embeds its creation time, which is the best guess we have.
+			_, err := db.Collection(blogCollection).UpdateMany(
+				ctx,
+				bson.M{"created_at": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.D{
+					{Key: "created_at", Value: bson.M{"$toDate": "$_id"}},
+					{Key: "updated_at", Value: bson.M{"$toDate": "$_id"}},
+				}}}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     3,
+		Description: "index blogs by creation time",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(blogCollection).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("created_at"),
+			})
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "expire idempotency keys",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			// Created with the default ttl, main applies the -idempotency-ttl flag
+			return (&mongoIdempotencyRecords{collection: db.Collection(idempotencyCollection)}).ensureIndexes(ctx, defaultIdempotencyTTL)
+		},
+	},
+}
+
+// latestSchemaVersion is the newest schema this binary understands.
+func latestSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// schemaVersion returns the highest migration recorded in the database.
+func schemaVersion(ctx context.Context, db *mongo.Database) (int, error) {
+	record := &migrationRecord{}
+	err := db.Collection(migrationsCollection).FindOne(
+		ctx,
+		bson.D{},
+		options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}),
+	).Decode(record)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return record.Version, nil
+}
+
+// migrate applies every pending migration in order and returns the resulting
+// schema version. It refuses to touch a database written by a newer binary.
+func migrate(ctx context.Context, db *mongo.Database) (int, error) {
+	current, err := schemaVersion(ctx, db)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read schema version: %v", err)
+	}
+	if latest := latestSchemaVersion(); current > latest {
+		return current, fmt.Errorf(
+			"database schema version %d is newer than the %d supported by this binary",
+			current, latest,
+		)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		fmt.Printf("Applying migration %d: %s\n", m.Version, m.Description)
+		if err := m.Up(ctx, db); err != nil {
+			return current, fmt.Errorf("migration %d failed: %v", m.Version, err)
+		}
+		_, err := db.Collection(migrationsCollection).InsertOne(ctx, migrationRecord{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   time.Now(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return current, fmt.Errorf("cannot record migration %d: %v", m.Version, err)
+		}
+		current = m.Version
+	}
+	return current, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const adminRole = "admin"
+
+// Authorization rules for blog mutations. They depend only on the caller and
+// the stored author, so they can be exercised without a gRPC server.
+var (
+	errUnauthenticated = errors.New("caller is not authenticated")
+	errNotOwner        = errors.New("caller is neither the author nor an admin")
+)
+
+// canCreate allows any authenticated caller to create a blog.
+func canCreate(caller *identity) error {
+	if caller == nil {
+		return errUnauthenticated
+	}
+	return nil
+}
+
+// canModify allows the author of a blog, or an admin, to update or delete it.
+func canModify(caller *identity, authorID string) error {
+	if caller == nil {
+		return errUnauthenticated
+	}
+	if caller.UserID == authorID || caller.hasRole(adminRole) {
+		return nil
+	}
+	return errNotOwner
+}
+
+// policyStatus converts a policy error into the matching gRPC status.
+func policyStatus(err error) error {
+	switch err {
+	case errUnauthenticated:
+		return status.Error(codes.Unauthenticated, "Authentication required")
+	case errNotOwner:
+		return status.Error(codes.PermissionDenied, "Only the author or an admin can modify this blog")
+	}
+	return status.Errorf(codes.Internal, "Unknown policy error: %v", err)
+}
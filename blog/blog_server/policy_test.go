@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestCanCreate(t *testing.T) {
+	if err := canCreate(nil); err != errUnauthenticated {
+		t.Errorf("canCreate(nil) = %v, want %v", err, errUnauthenticated)
+	}
+	if err := canCreate(&identity{UserID: "alice"}); err != nil {
+		t.Errorf("canCreate(alice) = %v, want nil", err)
+	}
+}
+
+func TestCanModify(t *testing.T) {
+	alice := &identity{UserID: "alice"}
+	admin := &identity{UserID: "root", Roles: []string{"editor", adminRole}}
+	editor := &identity{UserID: "bob", Roles: []string{"editor"}}
+
+	tests := []struct {
+		name     string
+		caller   *identity
+		authorID string
+		want     error
+	}{
+		{"anonymous", nil, "alice", errUnauthenticated},
+		{"author", alice, "alice", nil},
+		{"other user", alice, "bob", errNotOwner},
+		{"admin", admin, "alice", nil},
+		{"role other than admin", editor, "alice", errNotOwner},
+		{"blog without author", alice, "", errNotOwner},
+		{"admin on blog without author", admin, "", nil},
+		{"author id is case sensitive", alice, "Alice", errNotOwner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := canModify(tt.caller, tt.authorID); got != tt.want {
+				t.Errorf("canModify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{errUnauthenticated, codes.Unauthenticated},
+		{errNotOwner, codes.PermissionDenied},
+		{errors.New("unexpected"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(policyStatus(tt.err)); got != tt.want {
+			t.Errorf("policyStatus(%v) has code %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
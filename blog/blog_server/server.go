@@ -2,32 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/wiliamhw/golang-grpc-example/blog/blogpb"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
 const port = 50051
 
-var collection *mongo.Collection
+var (
+	tokensFile     = flag.String("tokens", "blog/tokens.json", "JSON file mapping bearer tokens to identities")
+	idempotencyTTL = flag.Duration("idempotency-ttl", defaultIdempotencyTTL, "How long idempotency keys are remembered")
+	cacheSize      = flag.Int("cache-size", 0, "Number of blogs kept in the read cache, 0 disables the cache")
+	cacheTTL       = flag.Duration("cache-ttl", time.Minute, "How long a cached blog stays fresh")
+	migrateOnly    = flag.Bool("migrate-only", false, "Apply pending schema migrations and exit")
+	drainTimeout   = flag.Duration("drain-timeout", 30*time.Second, "How long shutdown waits for in-flight RPCs")
+	debugAddr      = flag.String("debug-addr", "", "Address serving /debug/vars with the cache counters, empty to disable")
+)
+
+var store blogStore
 
 type blogItem struct {
 	ID       primitive.ObjectID `bson:"_id,omitempty"`
 	AuthorID string             `bson:"author_id"`
 	Content  string             `bson:"content"`
 	Title    string             `bson:"title"`
+
+	CreatedAt time.Time `bson:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
 }
 
 type server struct {
@@ -38,28 +56,28 @@ func (*server) CreateBlog(ctx context.Context, req *blogpb.CreateBlogRequest) (
 	*blogpb.CreteBlogResponse, error,
 ) {
 	fmt.Println("Create blog request")
+	caller := identityFromContext(ctx)
+	if err := canCreate(caller); err != nil {
+		return nil, policyStatus(err)
+	}
+
+	// The author is always the authenticated caller, never the request body.
 	blog := req.GetBlog()
+	now := time.Now()
 	data := blogItem{
-		AuthorID: blog.GetAuthorId(),
-		Title:    blog.GetTitle(),
-		Content:  blog.GetContent(),
+		AuthorID:  caller.UserID,
+		Title:     blog.GetTitle(),
+		Content:   blog.GetContent(),
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
-	res, err := collection.InsertOne(context.Background(), data)
-	if err != nil {
+	if err := store.Create(context.Background(), &data); err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			fmt.Sprintf("Internal error: %v", err),
 		)
 	}
-	oid, ok := res.InsertedID.(primitive.ObjectID)
-	if !ok {
-		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot convert to OID"),
-		)
-	}
-	data.ID = oid
 
 	return &blogpb.CreteBlogResponse{
 		Blog: dataToBlogPb(&data),
@@ -78,16 +96,9 @@ func (*server) ReadBlog(ctx context.Context, req *blogpb.ReadBlogRequest) (
 		)
 	}
 
-	// Create an empty struct
-	data := &blogItem{}
-	filter := bson.M{"_id": oid}
-
-	res := collection.FindOne(context.Background(), filter)
-	if err := res.Decode(data); err != nil {
-		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Cannot find blog with specified ID: %v", err),
-		)
+	data, err := findBlog(oid)
+	if err != nil {
+		return nil, err
 	}
 
 	return &blogpb.ReadBlogResponse{
@@ -108,25 +119,29 @@ func (*server) UpdateBlog(ctx context.Context, req *blogpb.UpdateBlogRequest) (
 		)
 	}
 
-	// Create an empty struct
-	data := &blogItem{}
-	filter := bson.M{"_id": oid}
+	data, err := findBlog(oid)
+	if err != nil {
+		return nil, err
+	}
 
-	res := collection.FindOne(context.Background(), filter)
-	if err := res.Decode(data); err != nil {
-		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Cannot find blog with specified ID: %v", err),
-		)
+	if err := canModify(identityFromContext(ctx), data.AuthorID); err != nil {
+		return nil, policyStatus(err)
 	}
 
-	// Update internal struct
-	data.AuthorID = blog.GetAuthorId()
+	// Update internal struct, the author cannot be changed
 	data.Content = blog.GetContent()
 	data.Title = blog.GetTitle()
+	data.UpdatedAt = time.Now()
 
-	_, updateErr := collection.ReplaceOne(context.Background(), filter, data)
-	if updateErr != nil {
+	err = store.Replace(context.Background(), data)
+	if err == errBlogNotFound {
+		// Deleted since findBlog
+		return nil, status.Errorf(
+			codes.NotFound,
+			fmt.Sprintf("Cannot find blog with specified ID: %v", oid.Hex()),
+		)
+	}
+	if err != nil {
 		return nil, status.Errorf(
 			codes.Internal,
 			fmt.Sprintf("Cannot update object in MongoDB: %v", err),
@@ -151,20 +166,25 @@ func (*server) DeleteBlog(ctx context.Context, req *blogpb.DeleteBlogRequest) (
 		)
 	}
 
-	// Create an empty struct
-	filter := bson.M{"_id": oid}
+	data, err := findBlog(oid)
+	if err != nil {
+		return nil, err
+	}
+	if err := canModify(identityFromContext(ctx), data.AuthorID); err != nil {
+		return nil, policyStatus(err)
+	}
 
-	res, deleteErr := collection.DeleteOne(context.Background(), filter)
-	if deleteErr != nil {
+	deleteErr := store.Delete(context.Background(), oid)
+	if deleteErr == errBlogNotFound {
 		return nil, status.Errorf(
-			codes.Internal,
-			fmt.Sprintf("Cannot delete object in MongoDB: %v", deleteErr),
+			codes.NotFound,
+			fmt.Sprintf("Blog not found: %v", blogId),
 		)
 	}
-	if res.DeletedCount == 0 {
+	if deleteErr != nil {
 		return nil, status.Errorf(
-			codes.NotFound,
-			fmt.Sprintf("Blog not found: %v", blogId),
+			codes.Internal,
+			fmt.Sprintf("Cannot delete object in MongoDB: %v", deleteErr),
 		)
 	}
 
@@ -179,38 +199,47 @@ func (*server) ListBlog(
 ) error {
 	fmt.Println("List blog request")
 
-	cur, err := collection.Find(context.Background(), bson.D{})
+	// The stream context ends the listing when the client goes away or the
+	// server is stopped
+	ctx := stream.Context()
+	err := store.List(ctx, func(data *blogItem) error {
+		if err := stream.Send(&blogpb.ListBlogResponse{Blog: dataToBlogPb(data)}); err != nil {
+			log.Printf("Cannot send blog %v to stream: %v", data.ID, err)
+			return err
+		}
+		return nil
+	})
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
 	if err != nil {
 		return status.Errorf(
 			codes.Internal,
 			fmt.Sprintf("Unknown internal error: %v", err),
 		)
 	}
-	defer func(cur *mongo.Cursor, ctx context.Context) {
-		if err := cur.Close(ctx); err != nil {
-			log.Printf("Failed to close MongoDB cursor: %v\n", err)
-		}
-	}(cur, context.Background())
-
-	for cur.Next(context.Background()) {
-		data := &blogItem{}
-		if err := cur.Decode(data); err != nil {
-			return status.Errorf(
-				codes.Internal,
-				fmt.Sprintf("Error while decoding data from MongoDB: %v", err),
-			)
-		}
-		if err := stream.Send(&blogpb.ListBlogResponse{Blog: dataToBlogPb(data)}); err != nil {
-			log.Printf("Cannot send blog %v to stream", data.ID)
-		}
+	return nil
+}
+
+// findBlog loads a blog and converts store errors into gRPC statuses.
+func findBlog(oid primitive.ObjectID) (*blogItem, error) {
+	data, err := store.Get(context.Background(), oid)
+	if err == errBlogNotFound {
+		return nil, status.Errorf(
+			codes.NotFound,
+			fmt.Sprintf("Cannot find blog with specified ID: %v", oid.Hex()),
+		)
 	}
-	if err := cur.Err(); err != nil {
-		return status.Errorf(
+	if err != nil {
+		return nil, status.Errorf(
 			codes.Internal,
-			fmt.Sprintf("Unknown internal error: %v", err),
+			fmt.Sprintf("Cannot read blog from MongoDB: %v", err),
 		)
 	}
-	return nil
+	return data, nil
 }
 
 func dataToBlogPb(data *blogItem) *blogpb.Blog {
@@ -225,6 +254,7 @@ func dataToBlogPb(data *blogItem) *blogpb.Blog {
 func main() {
 	// Show the file name and line number of error.
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	flag.Parse()
 
 	fmt.Println("Blog Service Started")
 
@@ -237,39 +267,137 @@ func main() {
 		log.Fatalf("Failed to connect to mongoDB: %v", err)
 	}
 
+	// Bring the schema up to date before serving
+	db := client.Database("mydb")
+	migrateCtx, migrateCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	version, err := migrate(migrateCtx, db)
+	migrateCancel()
+	if err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	fmt.Printf("Database schema is at version %d\n", version)
+	if *migrateOnly {
+		if err := client.Disconnect(context.Background()); err != nil {
+			log.Fatalf("Failed to disconnect from MongoDB: %v", err)
+		}
+		return
+	}
+
 	// Create database "mydb" and table "blog"
-	collection = client.Database("mydb").Collection("blog")
+	collection := db.Collection(blogCollection)
+	store = &mongoBlogStore{collection: collection}
+	if *cacheSize > 0 {
+		fmt.Printf("Caching up to %d blogs for %v\n", *cacheSize, *cacheTTL)
+		store = newCachedBlogStore(store, newLRUCache(*cacheSize, *cacheTTL))
+	}
+	if *debugAddr != "" {
+		go func() {
+			// expvar registers /debug/vars on the default mux
+			if err := http.ListenAndServe(*debugAddr, nil); err != nil {
+				log.Printf("Debug server stopped: %v\n", err)
+			}
+		}()
+	}
+
+	// Idempotency keys of mutations, expired by the TTL index of migration 4
+	idempotencyRecords := &mongoIdempotencyRecords{collection: db.Collection(idempotencyCollection)}
+	idempotency := newIdempotencyStore(
+		idempotencyRecords,
+		"/blog.BlogService/CreateBlog",
+		"/blog.BlogService/UpdateBlog",
+		"/blog.BlogService/DeleteBlog",
+	)
+	ttlCtx, ttlCancel := context.WithTimeout(context.Background(), 20*time.Second)
+	err = idempotencyRecords.ensureIndexes(ttlCtx, *idempotencyTTL)
+	ttlCancel()
+	if err != nil {
+		log.Fatalf("Failed to apply the idempotency TTL: %v", err)
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
+	tokens, err := loadTokens(*tokensFile)
+	if err != nil {
+		log.Fatalf("Failed to load tokens: %v", err)
+	}
+
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(tokens.unaryInterceptor, idempotency.unaryInterceptor),
+		grpc.StreamInterceptor(tokens.streamInterceptor),
+	)
 	blogpb.RegisterBlogServiceServer(s, &server{})
 
 	// Register reflection service on gRPC server.
 	reflection.Register(s)
 
+	// Health service, flipped to NOT_SERVING as soon as shutdown starts
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("blog.BlogService", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	serveErr := make(chan error, 1)
 	go func() {
 		fmt.Println("Starting Server...")
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("Failed to serve: %v", err)
-		}
+		serveErr <- s.Serve(lis)
 	}()
 
-	// Wait for control C to exit
+	// Wait for control C or a termination request to exit
 	ch := make(chan os.Signal, 1)
-	signal.Notify(ch, os.Interrupt)
-
-	// Block until a signal is received
-	<-ch
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	// Block until a signal is received or the server fails
+	failed := false
+	select {
+	case sig := <-ch:
+		fmt.Printf("Received %v\n", sig)
+	case err := <-serveErr:
+		log.Printf("Failed to serve: %v", err)
+		failed = true
+	}
 
 	fmt.Println("Stopping the server")
-	s.Stop()
+	healthServer.Shutdown()
+	if !gracefulStop(s, *drainTimeout) {
+		failed = true
+	}
 	fmt.Println("Closing the listener")
-	lis.Close()
+	// Serve closes the listener when it returns, so an already closed listener is fine
+	if err := lis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		log.Printf("Failed to close the listener: %v", err)
+		failed = true
+	}
 	fmt.Println("Closing MongoDB Connection")
-	client.Disconnect(context.TODO())
+	disconnectCtx, disconnectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer disconnectCancel()
+	if err := client.Disconnect(disconnectCtx); err != nil {
+		log.Printf("Failed to disconnect from MongoDB: %v", err)
+		failed = true
+	}
 	fmt.Println("End of Program")
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// gracefulStop waits for in-flight RPCs to finish, forcing them to stop once
+// timeout has elapsed. It returns false if the server had to be stopped forcefully.
+func gracefulStop(s *grpc.Server, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		log.Printf("In-flight requests did not finish within %v, forcing stop", timeout)
+		s.Stop()
+		<-done
+		return false
+	}
 }
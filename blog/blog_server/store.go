@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+)
+
+var errBlogNotFound = errors.New("blog not found")
+
+// blogStore persists blog items. Implementations return errBlogNotFound when
+// the requested blog does not exist.
+type blogStore interface {
+	Create(ctx context.Context, data *blogItem) error
+	Get(ctx context.Context, id primitive.ObjectID) (*blogItem, error)
+	Replace(ctx context.Context, data *blogItem) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	List(ctx context.Context, fn func(data *blogItem) error) error
+}
+
+// mongoBlogStore keeps blogs in a MongoDB collection.
+type mongoBlogStore struct {
+	collection *mongo.Collection
+}
+
+func (s *mongoBlogStore) Create(ctx context.Context, data *blogItem) error {
+	res, err := s.collection.InsertOne(ctx, data)
+	if err != nil {
+		return err
+	}
+	oid, ok := res.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return errors.New("cannot convert to OID")
+	}
+	data.ID = oid
+	return nil
+}
+
+func (s *mongoBlogStore) Get(ctx context.Context, id primitive.ObjectID) (*blogItem, error) {
+	data := &blogItem{}
+	err := s.collection.FindOne(ctx, bson.M{"_id": id}).Decode(data)
+	if err == mongo.ErrNoDocuments {
+		return nil, errBlogNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *mongoBlogStore) Replace(ctx context.Context, data *blogItem) error {
+	res, err := s.collection.ReplaceOne(ctx, bson.M{"_id": data.ID}, data)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errBlogNotFound
+	}
+	return nil
+}
+
+func (s *mongoBlogStore) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return errBlogNotFound
+	}
+	return nil
+}
+
+func (s *mongoBlogStore) List(ctx context.Context, fn func(data *blogItem) error) error {
+	cur, err := s.collection.Find(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	defer func(cur *mongo.Cursor, ctx context.Context) {
+		if err := cur.Close(ctx); err != nil {
+			log.Printf("Failed to close MongoDB cursor: %v\n", err)
+		}
+	}(cur, context.Background())
+
+	for cur.Next(ctx) {
+		data := &blogItem{}
+		if err := cur.Decode(data); err != nil {
+			return err
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
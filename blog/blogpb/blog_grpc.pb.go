@@ -22,9 +22,14 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BlogServiceClient interface {
+	// Mutations require an "authorization: Bearer <token>" metadata header.
+	// Retrying a mutation with the same idempotency key returns the original response,
+	// reusing the key with a different request returns INVALID_ARGUMENT.
 	CreateBlog(ctx context.Context, in *CreateBlogRequest, opts ...grpc.CallOption) (*CreteBlogResponse, error)
 	ReadBlog(ctx context.Context, in *ReadBlogRequest, opts ...grpc.CallOption) (*ReadBlogResponse, error)
+	// return NOT_FOUND if not found, PERMISSION_DENIED if the caller is neither the author nor an admin
 	UpdateBlog(ctx context.Context, in *UpdateBlogRequest, opts ...grpc.CallOption) (*UpdateBlogResponse, error)
+	// return NOT_FOUND if not found, PERMISSION_DENIED if the caller is neither the author nor an admin
 	DeleteBlog(ctx context.Context, in *DeleteBlogRequest, opts ...grpc.CallOption) (*DeleteBlogResponse, error)
 	ListBlog(ctx context.Context, in *ListBlogRequest, opts ...grpc.CallOption) (BlogService_ListBlogClient, error)
 }
@@ -109,9 +114,14 @@ func (x *blogServiceListBlogClient) Recv() (*ListBlogResponse, error) {
 // All implementations must embed UnimplementedBlogServiceServer
 // for forward compatibility
 type BlogServiceServer interface {
+	// Mutations require an "authorization: Bearer <token>" metadata header.
+	// Retrying a mutation with the same idempotency key returns the original response,
+	// reusing the key with a different request returns INVALID_ARGUMENT.
 	CreateBlog(context.Context, *CreateBlogRequest) (*CreteBlogResponse, error)
 	ReadBlog(context.Context, *ReadBlogRequest) (*ReadBlogResponse, error)
+	// return NOT_FOUND if not found, PERMISSION_DENIED if the caller is neither the author nor an admin
 	UpdateBlog(context.Context, *UpdateBlogRequest) (*UpdateBlogResponse, error)
+	// return NOT_FOUND if not found, PERMISSION_DENIED if the caller is neither the author nor an admin
 	DeleteBlog(context.Context, *DeleteBlogRequest) (*DeleteBlogResponse, error)
 	ListBlog(*ListBlogRequest, BlogService_ListBlogServer) error
 	mustEmbedUnimplementedBlogServiceServer()
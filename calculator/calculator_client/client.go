@@ -10,6 +10,7 @@ import (
 	"google.golang.org/grpc/status"
 	"io"
 	"log"
+	"math"
 	"time"
 )
 
@@ -38,8 +39,17 @@ func main() {
 	doUnary(c)
 	doServerStreaming(c)
 	doClientStreaming(c)
+	doStatistics(c)
 	doBiDiStreaming(c)
+	doAggregate(c, &calculatorpb.AggregationConfig{Mode: calculatorpb.AggregationMode_WINDOW_MAX, WindowSize: 3})
+	doAggregate(c, &calculatorpb.AggregationConfig{Mode: calculatorpb.AggregationMode_TOP_K, K: 3})
 	doErrorUnary(c)
+	doRoot(c)
+	doMatrices(c)
+	doConvert(c)
+	doEvaluate(c)
+	doSession(c)
+	doPrimes(c)
 }
 
 func doUnary(c calculatorpb.CalculatorServiceClient) {
@@ -53,15 +63,45 @@ func doUnary(c calculatorpb.CalculatorServiceClient) {
 		log.Fatalf("Error while calling Calculator RPC: %v", err)
 	}
 	log.Printf("Response from Calculator: %v", res.SumResult)
+
+	// Exact decimal sum, 0.1 + 0.2 is not 0.30000000000000004
+	res, err = c.Sum(context.Background(), &calculatorpb.SumRequest{
+		Mode:          calculatorpb.ArithmeticMode_DECIMAL,
+		FirstDecimal:  "0.1",
+		SecondDecimal: "0.2",
+	})
+	if err != nil {
+		log.Fatalf("Error while calling Calculator RPC: %v", err)
+	}
+	log.Printf("Decimal response from Calculator: %v", res.GetDecimalResult())
+
+	// Overflow is reported instead of wrapping around
+	_, err = c.Sum(context.Background(), &calculatorpb.SumRequest{
+		FirstNumber:  math.MaxInt64,
+		SecondNumber: 1,
+	})
+	if status.Code(err) == codes.OutOfRange {
+		log.Printf("Overflow was detected: %v", status.Convert(err).Message())
+	}
 }
 
 func doServerStreaming(c calculatorpb.CalculatorServiceClient) {
 	fmt.Println("Starting to do a Server Streaming RPC")
 
-	req := &calculatorpb.PrimeNumberDecompositionRequest{
-		Number: 124538982,
-	}
-	resStream, err := c.PrimeNumberDecomposition(context.Background(), req)
+	decompose(c, &calculatorpb.PrimeNumberDecompositionRequest{Number: 124538982})
+	// 2^61-1 is prime
+	decompose(c, &calculatorpb.PrimeNumberDecompositionRequest{Number: 2305843009213693951})
+	// 100000000003^2 * 700000000031, larger than an int64
+	decompose(c, &calculatorpb.PrimeNumberDecompositionRequest{
+		DecimalNumber: "7000000000730000000024900000000279",
+	})
+}
+
+func decompose(c calculatorpb.CalculatorServiceClient, req *calculatorpb.PrimeNumberDecompositionRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resStream, err := c.PrimeNumberDecomposition(ctx, req)
 	if err != nil {
 		log.Fatalf("Error while calling Calculator Server Streaming RPC: %v", err)
 	}
@@ -70,10 +110,21 @@ func doServerStreaming(c calculatorpb.CalculatorServiceClient) {
 		if err == io.EOF {
 			break
 		}
+		if status.Code(err) == codes.DeadlineExceeded {
+			log.Printf("PrimeNumberDecomposition ran out of time: %v", err)
+			break
+		}
 		if err != nil {
 			log.Fatalf("Error while reading stream %v", err)
 		}
-		log.Printf("Response from PrimeNumberDecomposition: %v", res.GetPrimeNumber())
+		if res.GetUnfactored() != "" {
+			log.Printf("Left unfactored: %v", res.GetUnfactored())
+			continue
+		}
+		log.Printf(
+			"Response from PrimeNumberDecomposition: %v^%v",
+			res.GetDecimalPrime(), res.GetMultiplicity(),
+		)
 	}
 }
 
@@ -85,7 +136,7 @@ func doClientStreaming(c calculatorpb.CalculatorServiceClient) {
 		log.Fatalf("Error while calling ComputeAverage: %v", err)
 	}
 
-	numbers := [...]int32{3, 5, 9, 54, 23}
+	numbers := [...]int64{3, 5, 9, 54, 23}
 
 	for _, number := range numbers {
 		fmt.Printf("Sending number: %v\n", number)
@@ -103,7 +154,34 @@ func doClientStreaming(c calculatorpb.CalculatorServiceClient) {
 	if err != nil {
 		log.Fatalf("Error while receiving response from ComputeAverage: %v", err)
 	}
-	fmt.Printf("The average is: %v\n", res.GetAverage())
+	fmt.Printf("The average is: %v (exactly %v)\n", res.GetAverage(), res.GetDecimalAverage())
+}
+
+func doStatistics(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do a ComputeStatistics Client Streaming RPC...")
+
+	stream, err := c.ComputeStatistics(context.Background())
+	if err != nil {
+		log.Fatalf("Error while calling ComputeStatistics: %v", err)
+	}
+
+	// Percentiles can be requested on any message
+	err = stream.Send(&calculatorpb.ComputeStatisticsRequest{Percentiles: []float64{25, 50, 75}, Number: 1})
+	if err != nil {
+		log.Fatalf("Error on sending request: %v\n", err)
+	}
+	for number := 2; number <= 1000; number++ {
+		err := stream.Send(&calculatorpb.ComputeStatisticsRequest{Number: float64(number)})
+		if err != nil {
+			log.Fatalf("Error on sending request: %v\n", err)
+		}
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		log.Fatalf("Error while receiving response from ComputeStatistics: %v", err)
+	}
+	fmt.Printf("Statistics: %v\n", res)
 }
 
 func doBiDiStreaming(c calculatorpb.CalculatorServiceClient) {
@@ -113,7 +191,7 @@ func doBiDiStreaming(c calculatorpb.CalculatorServiceClient) {
 	if err != nil {
 		log.Fatalf("Error while creating stream and calling FindMaximum: %v", err)
 	}
-	numbers := [...]int32{4, 7, 2, 19, 4, 6, 32}
+	numbers := [...]int64{4, 7, 2, 19, 4, 6, 32}
 
 	// Sender
 	go func() {
@@ -146,6 +224,47 @@ func doBiDiStreaming(c calculatorpb.CalculatorServiceClient) {
 	}
 }
 
+func doAggregate(c calculatorpb.CalculatorServiceClient, config *calculatorpb.AggregationConfig) {
+	fmt.Printf("Starting to do an Aggregate BiDi Streaming RPC with %v...\n", config)
+
+	stream, err := c.Aggregate(context.Background())
+	if err != nil {
+		log.Fatalf("Error while creating stream and calling Aggregate: %v", err)
+	}
+	numbers := [...]int64{-4, -7, -2, -19, -4, -6, -32}
+
+	// Sender
+	go func() {
+		for i, number := range numbers {
+			fmt.Printf("Sending number: %v\n", number)
+			req := &calculatorpb.AggregateRequest{Number: number}
+			if i == 0 {
+				req.Config = config
+			}
+			if err := stream.Send(req); err != nil {
+				log.Printf("Error while sending data to stream: %v\n", err)
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		err := stream.CloseSend()
+		if err != nil {
+			log.Fatalf("Error while closing send stream: %v\n", err)
+		}
+	}()
+
+	// Receiver
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error while receiving: %v\n", err)
+		}
+		fmt.Printf("Aggregate changed to: %v\n", res.GetValues())
+	}
+}
+
 func doErrorUnary(c calculatorpb.CalculatorServiceClient) {
 	fmt.Println("Starting to do a SquareRoot Unary RPC...")
 
@@ -176,3 +295,205 @@ func doErrorCall(c calculatorpb.CalculatorServiceClient, number int32) {
 	}
 	fmt.Printf("Result of square root of %v: %v\n", number, res.GetNumberRoot())
 }
+
+func doRoot(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do a Root Unary RPC...")
+
+	requests := [...]*calculatorpb.RootRequest{
+		{Radicand: 2, Precision: 50},
+		{Radicand: -27, Degree: 3},
+		{Radicand: -27, Degree: 3, Mode: calculatorpb.RootMode_COMPLEX},
+		{Radicand: -4, Mode: calculatorpb.RootMode_COMPLEX, Precision: 20},
+		{Radicand: -4},
+	}
+	for _, req := range requests {
+		res, err := c.Root(context.Background(), req)
+		if err != nil {
+			respErr, ok := status.FromError(err)
+			if !ok {
+				log.Fatalf("Big Error while calling Root RPC: %v", err)
+			}
+			fmt.Printf("Error message from server: %v (%v)\n", respErr.Message(), respErr.Code())
+			continue
+		}
+		if res.GetDecimalReal() != "" {
+			fmt.Printf("Root %v => %v + %vi\n", req, res.GetDecimalReal(), res.GetDecimalImaginary())
+			continue
+		}
+		fmt.Printf("Root %v => %v + %vi\n", req, res.GetReal(), res.GetImaginary())
+	}
+}
+
+func doMatrices(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do the linear algebra RPCs...")
+
+	a := &calculatorpb.Matrix{Rows: 3, Columns: 3, Values: []float64{2, 1, -1, -3, -1, 2, -2, 1, 2}}
+	singular := &calculatorpb.Matrix{Rows: 2, Columns: 2, Values: []float64{1, 2, 2, 4}}
+
+	det, err := c.Determinant(context.Background(), &calculatorpb.DeterminantRequest{Matrix: a})
+	if err != nil {
+		log.Fatalf("Error while calling Determinant RPC: %v", err)
+	}
+	fmt.Printf("Determinant: %v\n", det.GetDeterminant())
+
+	product, err := c.MultiplyMatrices(context.Background(), &calculatorpb.MultiplyMatricesRequest{A: a, B: a})
+	if err != nil {
+		log.Fatalf("Error while calling MultiplyMatrices RPC: %v", err)
+	}
+	fmt.Printf("Square: %v\n", product.GetResult().GetValues())
+
+	solution, err := c.SolveLinearSystem(context.Background(), &calculatorpb.SolveLinearSystemRequest{
+		A: a,
+		B: &calculatorpb.Vector{Values: []float64{8, -11, -3}},
+	})
+	if err != nil {
+		log.Fatalf("Error while calling SolveLinearSystem RPC: %v", err)
+	}
+	fmt.Printf("Solution: %v\n", solution.GetX().GetValues())
+
+	// Error calls
+	if _, err := c.Inverse(context.Background(), &calculatorpb.InverseRequest{Matrix: singular}); err != nil {
+		respErr, _ := status.FromError(err)
+		fmt.Printf("Error message from server: %v (%v)\n", respErr.Message(), respErr.Code())
+	}
+	if _, err := c.AddMatrices(context.Background(), &calculatorpb.AddMatricesRequest{A: a, B: singular}); err != nil {
+		respErr, _ := status.FromError(err)
+		fmt.Printf("Error message from server: %v (%v)\n", respErr.Message(), respErr.Code())
+	}
+}
+
+func doConvert(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do the unit conversion RPCs...")
+
+	units, err := c.ListUnits(context.Background(), &calculatorpb.ListUnitsRequest{Dimension: "temperature"})
+	if err != nil {
+		log.Fatalf("Error while calling ListUnits RPC: %v", err)
+	}
+	for _, unit := range units.GetUnits() {
+		fmt.Printf("%v (%v)\n", unit.GetSymbol(), unit.GetName())
+	}
+
+	requests := [...]*calculatorpb.ConvertRequest{
+		{Value: 42.195, FromUnit: "km", ToUnit: "mi"},
+		{Value: 100, FromUnit: "C", ToUnit: "F"},
+		{Value: 1, FromUnit: "GiB", ToUnit: "MB"},
+		{Value: 1, FromUnit: "km", ToUnit: "kg"},
+	}
+	for _, req := range requests {
+		res, err := c.Convert(context.Background(), req)
+		if err != nil {
+			respErr, _ := status.FromError(err)
+			fmt.Printf("Error message from server: %v (%v)\n", respErr.Message(), respErr.Code())
+			continue
+		}
+		fmt.Printf("%v %v = %v %v\n", req.GetValue(), req.GetFromUnit(), res.GetValue(), req.GetToUnit())
+	}
+}
+
+func doEvaluate(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do an Evaluate Unary RPC...")
+
+	expressions := [...]string{
+		"2 * (3 + sqrt(16)) ^ 2 - max(1, pi)",
+		"-2 ^ 2 + log(e)",
+		"1 / (2 - 2)",
+		"3 * (4 + ",
+	}
+	for _, expression := range expressions {
+		res, err := c.Evaluate(context.Background(), &calculatorpb.EvaluateRequest{Expression: expression})
+		if err != nil {
+			log.Fatalf("Error while calling Evaluate RPC: %v", err)
+		}
+		if evalErr := res.GetError(); evalErr != nil {
+			fmt.Printf("%v => error at position %v: %v\n", expression, evalErr.GetPosition(), evalErr.GetMessage())
+			continue
+		}
+		fmt.Printf("%v => %v\n", expression, res.GetResult())
+	}
+}
+
+func doSession(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do a CalculatorSession BiDi Streaming RPC...")
+
+	sessionId := runSession(c, "", []string{"y = 4", "x = 3 * y", "x + 1", "z + 1"})
+
+	// Reconnect and keep using the variables of the first stream
+	fmt.Printf("Resuming session %v\n", sessionId)
+	runSession(c, sessionId, []string{"x * y"})
+}
+
+// runSession sends the statements one at a time and returns the session id.
+func runSession(c calculatorpb.CalculatorServiceClient, sessionId string, statements []string) string {
+	stream, err := c.CalculatorSession(context.Background())
+	if err != nil {
+		log.Fatalf("Error while creating stream and calling CalculatorSession: %v", err)
+	}
+
+	for _, statement := range statements {
+		err := stream.Send(&calculatorpb.CalculatorSessionRequest{
+			SessionId: sessionId,
+			Statement: statement,
+		})
+		if err != nil {
+			log.Fatalf("Error while sending data to stream: %v\n", err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			log.Fatalf("Error while receiving: %v\n", err)
+		}
+		sessionId = res.GetSessionId()
+		if evalErr := res.GetError(); evalErr != nil {
+			fmt.Printf("%v => error at position %v: %v\n", statement, evalErr.GetPosition(), evalErr.GetMessage())
+			continue
+		}
+		fmt.Printf("%v => %v\n", statement, res.GetResult())
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		log.Fatalf("Error while closing send stream: %v\n", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		log.Fatalf("Unexpected end of session: %v\n", err)
+	}
+	return sessionId
+}
+
+func doPrimes(c calculatorpb.CalculatorServiceClient) {
+	fmt.Println("Starting to do the prime RPCs...")
+
+	for _, number := range [...]string{"2305843009213693951", "170141183460469231731687303715884105727", "1000000007000000063"} {
+		res, err := c.IsPrime(context.Background(), &calculatorpb.IsPrimeRequest{DecimalNumber: number})
+		if err != nil {
+			log.Fatalf("Error while calling IsPrime RPC: %v", err)
+		}
+		fmt.Printf("Is %v prime? %v (certainty %v)\n", number, res.GetIsPrime(), res.GetCertainty())
+	}
+
+	next, err := c.NextPrime(context.Background(), &calculatorpb.NextPrimeRequest{Number: 1000000000000})
+	if err != nil {
+		log.Fatalf("Error while calling NextPrime RPC: %v", err)
+	}
+	fmt.Printf("The next prime after 10^12 is %v\n", next.GetDecimalPrime())
+
+	stream, err := c.GeneratePrimes(context.Background(), &calculatorpb.GeneratePrimesRequest{
+		From: 1,
+		To:   10000000,
+	})
+	if err != nil {
+		log.Fatalf("Error while calling GeneratePrimes RPC: %v", err)
+	}
+	count := 0
+	var last int64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error while reading stream %v", err)
+		}
+		count += len(res.GetPrimes())
+		last = res.GetPrimes()[len(res.GetPrimes())-1]
+	}
+	fmt.Printf("There are %v primes up to 10^7, the largest is %v\n", count, last)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"container/heap"
+	"errors"
+	"sort"
+	"time"
+)
+
+// Limits keeping the memory of a single aggregation stream bounded.
+const (
+	maxWindowValues = 100000
+	maxWindowSpan   = 24 * time.Hour
+	maxTopK         = 1000
+)
+
+var errWindowTooLarge = errors.New("the window holds too many candidate values")
+
+// aggregator maintains the result of an Aggregate stream.
+type aggregator interface {
+	// add fails once the aggregation would need more than its memory limits.
+	add(x int64, now time.Time) error
+	// expire drops values that left a time window, it is a no-op otherwise.
+	expire(now time.Time)
+	// nextExpiry is when the oldest value leaves a time window.
+	nextExpiry() (time.Time, bool)
+	result() []int64
+}
+
+// runningExtreme keeps the maximum, or the minimum when less is reversed, of every value.
+type runningExtreme struct {
+	better func(a, b int64) bool
+	value  int64
+	seen   bool
+}
+
+func (a *runningExtreme) add(x int64, _ time.Time) error {
+	if !a.seen || a.better(x, a.value) {
+		a.value = x
+		a.seen = true
+	}
+	return nil
+}
+
+func (a *runningExtreme) expire(time.Time) {}
+
+func (a *runningExtreme) nextExpiry() (time.Time, bool) { return time.Time{}, false }
+
+func (a *runningExtreme) result() []int64 {
+	if !a.seen {
+		return nil
+	}
+	return []int64{a.value}
+}
+
+// windowExtreme keeps the maximum (or minimum) of the last size values and/or
+// the values of the last span, using a monotonic queue so every value is
+// pushed and popped at most once.
+type windowExtreme struct {
+	better func(a, b int64) bool
+	size   int
+	span   time.Duration
+
+	seq   int64
+	queue []windowEntry // values in arrival order, each better than the ones after it
+}
+
+type windowEntry struct {
+	value int64
+	seq   int64
+	at    time.Time
+}
+
+func (a *windowExtreme) add(x int64, now time.Time) error {
+	a.seq++
+	// Values that are not better than x can never be the result again
+	for len(a.queue) > 0 && !a.better(a.queue[len(a.queue)-1].value, x) {
+		a.queue = a.queue[:len(a.queue)-1]
+	}
+	a.queue = append(a.queue, windowEntry{value: x, seq: a.seq, at: now})
+
+	for len(a.queue) > 0 && a.size > 0 && a.queue[0].seq <= a.seq-int64(a.size) {
+		a.queue = a.queue[1:]
+	}
+	a.expire(now)
+	// Dropping the head would silently report a wrong extreme
+	if len(a.queue) > maxWindowValues {
+		return errWindowTooLarge
+	}
+	return nil
+}
+
+func (a *windowExtreme) expire(now time.Time) {
+	if a.span <= 0 {
+		return
+	}
+	for len(a.queue) > 0 && !now.Before(a.queue[0].at.Add(a.span)) {
+		a.queue = a.queue[1:]
+	}
+}
+
+func (a *windowExtreme) nextExpiry() (time.Time, bool) {
+	if a.span <= 0 || len(a.queue) == 0 {
+		return time.Time{}, false
+	}
+	return a.queue[0].at.Add(a.span), true
+}
+
+func (a *windowExtreme) result() []int64 {
+	if len(a.queue) == 0 {
+		return nil
+	}
+	return []int64{a.queue[0].value}
+}
+
+// topK keeps the k largest values seen so far in a min-heap.
+type topK struct {
+	k    int
+	heap int64Heap
+}
+
+func (a *topK) add(x int64, _ time.Time) error {
+	if len(a.heap) < a.k {
+		heap.Push(&a.heap, x)
+		return nil
+	}
+	if x > a.heap[0] {
+		a.heap[0] = x
+		heap.Fix(&a.heap, 0)
+	}
+	return nil
+}
+
+func (a *topK) expire(time.Time) {}
+
+func (a *topK) nextExpiry() (time.Time, bool) { return time.Time{}, false }
+
+// result returns the values in decreasing order.
+func (a *topK) result() []int64 {
+	values := append([]int64(nil), a.heap...)
+	sort.Slice(values, func(i, j int) bool { return values[i] > values[j] })
+	return values
+}
+
+type int64Heap []int64
+
+func (h int64Heap) Len() int            { return len(h) }
+func (h int64Heap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h int64Heap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *int64Heap) Push(x interface{}) { *h = append(*h, x.(int64)) }
+func (h *int64Heap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func greater(a, b int64) bool { return a > b }
+
+func less(a, b int64) bool { return a < b }
+
+func equalResults(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"github.com/wiliamhw/golang-grpc-example/calculator/calculatorpb"
+	"testing"
+	"time"
+)
+
+func TestRunningExtreme(t *testing.T) {
+	max, min := &runningExtreme{better: greater}, &runningExtreme{better: less}
+	if max.result() != nil {
+		t.Errorf("result() before any value = %v, want nil", max.result())
+	}
+	now := time.Now()
+	for _, x := range []int64{-5, -9, -2, -7} {
+		max.add(x, now)
+		min.add(x, now)
+	}
+	if got := max.result(); !equalResults(got, []int64{-2}) {
+		t.Errorf("running max = %v, want [-2]", got)
+	}
+	if got := min.result(); !equalResults(got, []int64{-9}) {
+		t.Errorf("running min = %v, want [-9]", got)
+	}
+}
+
+func TestWindowExtremeBySize(t *testing.T) {
+	a := &windowExtreme{better: greater, size: 3}
+	now := time.Now()
+	want := []int64{1, 3, 3, 3, 5, 5, 5, 4}
+	for i, x := range []int64{1, 3, 2, 1, 5, 4, 1, 2} {
+		if err := a.add(x, now); err != nil {
+			t.Fatalf("add(%d) failed: %v", x, err)
+		}
+		if got := a.result(); !equalResults(got, []int64{want[i]}) {
+			t.Errorf("max of the window ending with value %d = %v, want %d", i, got, want[i])
+		}
+	}
+}
+
+func TestWindowExtremeBySpan(t *testing.T) {
+	a := &windowExtreme{better: less, span: time.Second}
+	start := time.Now()
+	a.add(1, start)
+	a.add(5, start.Add(500*time.Millisecond))
+	a.add(3, start.Add(900*time.Millisecond))
+
+	if when, ok := a.nextExpiry(); !ok || !when.Equal(start.Add(time.Second)) {
+		t.Errorf("nextExpiry() = %v, %v, want %v", when, ok, start.Add(time.Second))
+	}
+	a.expire(start.Add(time.Second))
+	if got := a.result(); !equalResults(got, []int64{3}) {
+		t.Errorf("min after the first value expired = %v, want [3]", got)
+	}
+	a.expire(start.Add(2 * time.Second))
+	if got := a.result(); got != nil {
+		t.Errorf("min after every value expired = %v, want nil", got)
+	}
+	if _, ok := a.nextExpiry(); ok {
+		t.Errorf("nextExpiry() of an empty window is set")
+	}
+}
+
+func TestWindowExtremeTooManyCandidates(t *testing.T) {
+	// A decreasing run keeps every value a candidate for the max
+	a := &windowExtreme{better: greater, span: time.Hour}
+	now := time.Now()
+	for i := 0; i < maxWindowValues; i++ {
+		if err := a.add(int64(-i), now); err != nil {
+			t.Fatalf("add() failed after %d values: %v", i, err)
+		}
+	}
+	if err := a.add(-maxWindowValues, now); err != errWindowTooLarge {
+		t.Errorf("add() = %v, want %v", err, errWindowTooLarge)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	a := &topK{k: 3}
+	now := time.Now()
+	a.add(4, now)
+	if got := a.result(); !equalResults(got, []int64{4}) {
+		t.Errorf("top 3 of one value = %v, want [4]", got)
+	}
+	for _, x := range []int64{-1, 9, 4, 7, 2} {
+		a.add(x, now)
+	}
+	if got := a.result(); !equalResults(got, []int64{9, 7, 4}) {
+		t.Errorf("top 3 = %v, want [9 7 4]", got)
+	}
+}
+
+func TestNewAggregator(t *testing.T) {
+	invalid := []*calculatorpb.AggregationConfig{
+		{Mode: calculatorpb.AggregationMode_WINDOW_MAX},
+		{Mode: calculatorpb.AggregationMode_WINDOW_MAX, WindowSize: -1},
+		{Mode: calculatorpb.AggregationMode_WINDOW_MIN, WindowSize: maxWindowValues + 1},
+		{Mode: calculatorpb.AggregationMode_WINDOW_MIN, WindowMillis: 1 << 62},
+		{Mode: calculatorpb.AggregationMode_TOP_K},
+		{Mode: calculatorpb.AggregationMode_TOP_K, K: maxTopK + 1},
+		{Mode: 42},
+	}
+	for _, config := range invalid {
+		if _, err := newAggregator(config); err == nil {
+			t.Errorf("newAggregator(%v) succeeded, want an error", config)
+		}
+	}
+
+	valid := []*calculatorpb.AggregationConfig{
+		nil,
+		{Mode: calculatorpb.AggregationMode_RUNNING_MIN},
+		{Mode: calculatorpb.AggregationMode_WINDOW_MAX, WindowSize: 10, WindowMillis: 1000},
+		{Mode: calculatorpb.AggregationMode_TOP_K, K: maxTopK},
+	}
+	for _, config := range valid {
+		if _, err := newAggregator(config); err != nil {
+			t.Errorf("newAggregator(%v) failed: %v", config, err)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+)
+
+// Limits keeping exact arithmetic cheap enough for the server.
+const (
+	maxDecimalLength = 1000    // characters in a decimal operand
+	maxDecimalBits   = 1 << 16 // size of any intermediate result
+	maxDecimalPower  = 10000   // absolute value of an exponent in decimal mode
+
+	// Results without a finite decimal expansion, such as 1/3, are rounded to this many fractional digits.
+	decimalFractionDigits = 34
+)
+
+// Exponents are bounded so a short literal cannot expand into a huge number.
+var decimalPattern = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d{1,4})?$`)
+
+// checkedAdd returns a + b and false if the addition overflows.
+func checkedAdd(a, b int64) (int64, bool) {
+	sum := a + b
+	if (b > 0 && sum < a) || (b < 0 && sum > a) {
+		return 0, false
+	}
+	return sum, true
+}
+
+// parseDecimal parses an exact decimal number such as "-12.50" or "1e-3".
+func parseDecimal(s string) (*big.Rat, error) {
+	if len(s) > maxDecimalLength {
+		return nil, fmt.Errorf("decimal is %d characters long, the limit is %d", len(s), maxDecimalLength)
+	}
+	if !decimalPattern.MatchString(s) {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+// decimalOperand parses s, or uses the integer fallback when s is empty.
+func decimalOperand(s string, fallback int64) (*big.Rat, error) {
+	if s == "" {
+		return new(big.Rat).SetInt64(fallback), nil
+	}
+	return parseDecimal(s)
+}
+
+// formatDecimal prints r exactly when it has a finite decimal expansion and
+// rounds it to decimalFractionDigits otherwise.
+func formatDecimal(r *big.Rat) string {
+	denom := new(big.Int).Set(r.Denom())
+	twos, fives := 0, 0
+	five := big.NewInt(5)
+	mod := new(big.Int)
+	for denom.Bit(0) == 0 {
+		denom.Rsh(denom, 1)
+		twos++
+	}
+	for {
+		q, m := new(big.Int).QuoRem(denom, five, mod)
+		if m.Sign() != 0 {
+			break
+		}
+		denom = q
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return r.FloatString(decimalFractionDigits)
+	}
+	digits := twos
+	if fives > digits {
+		digits = fives
+	}
+	return r.FloatString(digits)
+}
+
+func checkDecimalSize(pos int, r *big.Rat) error {
+	if r.Num().BitLen()+r.Denom().BitLen() > maxDecimalBits {
+		return &limitError{Msg: fmt.Sprintf("intermediate result at position %d is too large", pos)}
+	}
+	return nil
+}
+
+// truncRat rounds r toward zero.
+func truncRat(r *big.Rat) *big.Int {
+	return new(big.Int).Quo(r.Num(), r.Denom())
+}
+
+// floorRat rounds r toward negative infinity. Div is Euclidean division,
+// which is floor division for the always positive denominator.
+func floorRat(r *big.Rat) *big.Int {
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
+
+// exactFunctions are the functions with an exact result in decimal mode.
+var exactFunctions = map[string]bool{
+	"abs":   true,
+	"min":   true,
+	"max":   true,
+	"floor": true,
+	"ceil":  true,
+	"round": true,
+}
+
+// evaluateDecimal computes the exact value of a parsed expression. Only
+// operations with exact results are supported, so irrational constants and
+// functions such as sqrt or log are rejected.
+func evaluateDecimal(node exprNode) (*big.Rat, error) {
+	switch n := node.(type) {
+	case *numberNode:
+		r, err := parseDecimal(n.text)
+		if err != nil {
+			return nil, &exprError{Pos: n.pos, Msg: err.Error()}
+		}
+		return r, nil
+	case *identNode:
+		if _, ok := constants[n.name]; ok {
+			return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf("constant %q has no exact decimal value", n.name)}
+		}
+		return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown name %q", n.name)}
+	case *unaryNode:
+		x, err := evaluateDecimal(n.x)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "-" {
+			return x.Neg(x), nil
+		}
+		return x, nil
+	case *binaryNode:
+		l, err := evaluateDecimal(n.l)
+		if err != nil {
+			return nil, err
+		}
+		r, err := evaluateDecimal(n.r)
+		if err != nil {
+			return nil, err
+		}
+		var v *big.Rat
+		switch n.op {
+		case "+":
+			v = new(big.Rat).Add(l, r)
+		case "-":
+			v = new(big.Rat).Sub(l, r)
+		case "*":
+			v = new(big.Rat).Mul(l, r)
+		case "/":
+			if r.Sign() == 0 {
+				return nil, &exprError{Pos: n.pos, Msg: "division by zero"}
+			}
+			v = new(big.Rat).Quo(l, r)
+		case "%":
+			if r.Sign() == 0 {
+				return nil, &exprError{Pos: n.pos, Msg: "modulo by zero"}
+			}
+			// Same sign convention as math.Mod: l - r*trunc(l/r)
+			q := new(big.Rat).SetInt(truncRat(new(big.Rat).Quo(l, r)))
+			v = new(big.Rat).Sub(l, q.Mul(q, r))
+		case "^":
+			v, err = powRat(n.pos, l, r)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown operator %q", n.op)}
+		}
+		if err := checkDecimalSize(n.pos, v); err != nil {
+			return nil, err
+		}
+		return v, nil
+	case *callNode:
+		fn, ok := functions[n.name]
+		if !ok {
+			return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown function %q", n.name)}
+		}
+		if !exactFunctions[n.name] {
+			return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf("function %q has no exact decimal result", n.name)}
+		}
+		if len(n.args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.args) > fn.maxArgs) {
+			return nil, &exprError{Pos: n.pos, Msg: fmt.Sprintf(
+				"wrong number of arguments to %s: %d", n.name, len(n.args),
+			)}
+		}
+		args := make([]*big.Rat, len(n.args))
+		for i, arg := range n.args {
+			v, err := evaluateDecimal(arg)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		return callDecimal(n.name, args), nil
+	}
+	return nil, &exprError{Pos: node.position(), Msg: "unknown expression"}
+}
+
+func callDecimal(name string, args []*big.Rat) *big.Rat {
+	x := args[0]
+	switch name {
+	case "abs":
+		return new(big.Rat).Abs(x)
+	case "min", "max":
+		m := x
+		for _, v := range args[1:] {
+			if (name == "min" && v.Cmp(m) < 0) || (name == "max" && v.Cmp(m) > 0) {
+				m = v
+			}
+		}
+		return m
+	case "floor":
+		return new(big.Rat).SetInt(floorRat(x))
+	case "ceil":
+		return new(big.Rat).Neg(new(big.Rat).SetInt(floorRat(new(big.Rat).Neg(x))))
+	case "round":
+		// Half away from zero, like math.Round
+		half := new(big.Rat).SetFrac64(1, 2)
+		if x.Sign() < 0 {
+			return new(big.Rat).Neg(new(big.Rat).SetInt(floorRat(new(big.Rat).Add(new(big.Rat).Neg(x), half))))
+		}
+		return new(big.Rat).SetInt(floorRat(new(big.Rat).Add(x, half)))
+	}
+	return nil
+}
+
+// powRat raises base to an integer exponent by repeated squaring, checking
+// the size of every intermediate result.
+func powRat(pos int, base, exponent *big.Rat) (*big.Rat, error) {
+	if !exponent.IsInt() {
+		return nil, &exprError{Pos: pos, Msg: "exponent must be an integer in decimal mode"}
+	}
+	if exponent.Num().CmpAbs(big.NewInt(maxDecimalPower)) > 0 {
+		return nil, &limitError{Msg: fmt.Sprintf("exponent at position %d is larger than %d", pos, maxDecimalPower)}
+	}
+	e := exponent.Num().Int64()
+	if e < 0 {
+		if base.Sign() == 0 {
+			return nil, &exprError{Pos: pos, Msg: "division by zero"}
+		}
+		base = new(big.Rat).Inv(base)
+		e = -e
+	}
+
+	result := new(big.Rat).SetInt64(1)
+	square := new(big.Rat).Set(base)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result.Mul(result, square)
+			if err := checkDecimalSize(pos, result); err != nil {
+				return nil, err
+			}
+		}
+		if e > 1 {
+			square.Mul(square, square)
+			if err := checkDecimalSize(pos, square); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
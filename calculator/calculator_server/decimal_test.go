@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCheckedAdd(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want int64
+		ok   bool
+	}{
+		{3, 10, 13, true},
+		{-3, -10, -13, true},
+		{math.MaxInt64, 0, math.MaxInt64, true},
+		{math.MaxInt64, 1, 0, false},
+		{math.MinInt64, -1, 0, false},
+		{math.MaxInt64, math.MinInt64, -1, true},
+		{math.MinInt64, math.MinInt64, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := checkedAdd(tt.a, tt.b)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("checkedAdd(%d, %d) = %d, %v, want %d, %v", tt.a, tt.b, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	valid := map[string]string{
+		"0":       "0",
+		"-12.50":  "-12.5",
+		"+.5":     "0.5",
+		"1e-3":    "0.001",
+		"2.5E+2":  "250",
+		"7.":      "7",
+		"0.1":     "0.1",
+		"1e9999":  "",
+		"-0.0000": "0",
+	}
+	for s, want := range valid {
+		r, err := parseDecimal(s)
+		if err != nil {
+			t.Errorf("parseDecimal(%q) failed: %v", s, err)
+			continue
+		}
+		if want != "" && formatDecimal(r) != want {
+			t.Errorf("parseDecimal(%q) = %v, want %v", s, formatDecimal(r), want)
+		}
+	}
+
+	for _, s := range []string{"", "abc", "1/3", "0x10", "1e", "1e10000", "--1", "1.2.3", " 1"} {
+		if _, err := parseDecimal(s); err == nil {
+			t.Errorf("parseDecimal(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		num, denom int64
+		want       string
+	}{
+		{1, 4, "0.25"},
+		{1, 8, "0.125"},
+		{3, 5, "0.6"},
+		{-7, 20, "-0.35"},
+		{42, 1, "42"},
+		{1, 3, "0.3333333333333333333333333333333333"},
+		{2, 3, "0.6666666666666666666666666666666667"},
+	}
+	for _, tt := range tests {
+		r, _ := parseDecimal("0")
+		r.SetFrac64(tt.num, tt.denom)
+		if got := formatDecimal(r); got != tt.want {
+			t.Errorf("formatDecimal(%d/%d) = %v, want %v", tt.num, tt.denom, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateDecimal(t *testing.T) {
+	tests := map[string]string{
+		"0.1 + 0.2":       "0.3",
+		"1 / 3 * 3":       "1",
+		"-7 % 3":          "-1",
+		"2 ^ -2":          "0.25",
+		"round(-2.5)":     "-3",
+		"ceil(-0.5)":      "0",
+		"floor(-0.5)":     "-1",
+		"max(1, 2.5, -3)": "2.5",
+	}
+	for src, want := range tests {
+		node, err := parseExpression(src)
+		if err != nil {
+			t.Fatalf("parseExpression(%q) failed: %v", src, err)
+		}
+		r, err := evaluateDecimal(node)
+		if err != nil {
+			t.Errorf("evaluateDecimal(%q) failed: %v", src, err)
+			continue
+		}
+		if got := formatDecimal(r); got != want {
+			t.Errorf("evaluateDecimal(%q) = %v, want %v", src, got, want)
+		}
+	}
+
+	for _, src := range []string{"1 / 0", "2 ^ 0.5", "sqrt(4)", "pi", "10 ^ 100000", "0 ^ -1"} {
+		node, err := parseExpression(src)
+		if err != nil {
+			t.Fatalf("parseExpression(%q) failed: %v", src, err)
+		}
+		if _, err := evaluateDecimal(node); err == nil {
+			t.Errorf("evaluateDecimal(%q) succeeded, want an error", src)
+		}
+	}
+}
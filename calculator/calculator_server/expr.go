@@ -0,0 +1,543 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits protecting the server from expressions that are expensive to parse.
+const (
+	maxExpressionLength = 1024
+	maxNestingDepth     = 64
+)
+
+// exprError is a parse or evaluation error at a byte offset of the expression.
+type exprError struct {
+	Pos int
+	Msg string
+}
+
+func (e *exprError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// limitError is returned when an expression exceeds one of the server limits.
+type limitError struct {
+	Msg string
+}
+
+func (e *limitError) Error() string {
+	return e.Msg
+}
+
+// Tokens
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case c == utf8.RuneError && size == 1:
+			return nil, &exprError{Pos: i, Msg: "invalid UTF-8"}
+		case unicode.IsSpace(c):
+			i += size
+		case isDigit(c) || c == '.':
+			start := i
+			for i < len(src) && (isDigit(rune(src[i])) || src[i] == '.') {
+				i++
+			}
+			// Exponent, e.g. 1e-3
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				j := i + 1
+				if j < len(src) && (src[j] == '+' || src[j] == '-') {
+					j++
+				}
+				if j < len(src) && isDigit(rune(src[j])) {
+					for j < len(src) && isDigit(rune(src[j])) {
+						j++
+					}
+					i = j
+				}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:i], pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(src) {
+				c, size := utf8.DecodeRuneInString(src[i:])
+				if !unicode.IsLetter(c) && !isDigit(c) && c != '_' {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+		case strings.ContainsRune("+-*/%^(),=", c):
+			tokens = append(tokens, token{kind: tokenOperator, text: string(c), pos: i})
+			i++
+		default:
+			return nil, &exprError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// isDigit only accepts ASCII digits, which are the only ones strconv parses.
+func isDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
+
+// Syntax tree
+
+type exprNode interface {
+	position() int
+}
+
+type numberNode struct {
+	pos  int
+	text string
+}
+
+type identNode struct {
+	pos  int
+	name string
+}
+
+type unaryNode struct {
+	pos int
+	op  string
+	x   exprNode
+}
+
+type binaryNode struct {
+	pos  int
+	op   string
+	l, r exprNode
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []exprNode
+}
+
+func (n *numberNode) position() int { return n.pos }
+func (n *identNode) position() int  { return n.pos }
+func (n *unaryNode) position() int  { return n.pos }
+func (n *binaryNode) position() int { return n.pos }
+func (n *callNode) position() int   { return n.pos }
+
+// Parser
+//
+//	expr    := term (('+' | '-') term)*
+//	term    := unary (('*' | '/' | '%') unary)*
+//	unary   := ('-' | '+') unary | power
+//	power   := primary ('^' unary)?
+//	primary := number | ident | ident '(' expr (',' expr)* ')' | '(' expr ')'
+type parser struct {
+	tokens []token
+	next   int
+	depth  int
+}
+
+// parseExpression parses a whole expression, enforcing the length and depth limits.
+func parseExpression(src string) (exprNode, error) {
+	p, err := newParser(src)
+	if err != nil {
+		return nil, err
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectEOF(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// parseStatement parses either an assignment "name = expr" or a bare expression,
+// in which case name is empty.
+func parseStatement(src string) (name string, node exprNode, err error) {
+	p, err := newParser(src)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(p.tokens) > 2 && p.tokens[0].kind == tokenIdent &&
+		p.tokens[1].kind == tokenOperator && p.tokens[1].text == "=" {
+		target := p.tokens[0]
+		if _, ok := constants[target.text]; ok {
+			return "", nil, &exprError{Pos: target.pos, Msg: fmt.Sprintf("cannot assign to constant %q", target.text)}
+		}
+		if _, ok := functions[target.text]; ok {
+			return "", nil, &exprError{Pos: target.pos, Msg: fmt.Sprintf("cannot assign to function %q", target.text)}
+		}
+		name = target.text
+		p.next = 2
+	}
+	node, err = p.parseExpr()
+	if err != nil {
+		return "", nil, err
+	}
+	if err := p.expectEOF(); err != nil {
+		return "", nil, err
+	}
+	return name, node, nil
+}
+
+func newParser(src string) (*parser, error) {
+	if len(src) > maxExpressionLength {
+		return nil, &limitError{Msg: fmt.Sprintf(
+			"expression is %d bytes long, the limit is %d", len(src), maxExpressionLength,
+		)}
+	}
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) isOperator(ops ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOperator {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expectEOF() error {
+	if t := p.peek(); t.kind != tokenEOF {
+		return &exprError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return nil
+}
+
+func (p *parser) enter() error {
+	p.depth++
+	if p.depth > maxNestingDepth {
+		return &limitError{Msg: fmt.Sprintf(
+			"expression is nested deeper than %d levels at position %d", maxNestingDepth, p.peek().pos,
+		)}
+	}
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseExpr() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("+", "-") {
+		op := p.advance()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("*", "/", "%") {
+		op := p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, l: left, r: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (exprNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	if p.isOperator("-", "+") {
+		op := p.advance()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: op.pos, op: op.text, x: x}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *parser) parsePower() (exprNode, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOperator("^") {
+		return base, nil
+	}
+	// Right associative, 2^3^2 is 2^(3^2)
+	op := p.advance()
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{pos: op.pos, op: op.text, l: base, r: exponent}, nil
+}
+
+func (p *parser) parsePrimary() (exprNode, error) {
+	t := p.advance()
+	switch {
+	case t.kind == tokenNumber:
+		return &numberNode{pos: t.pos, text: t.text}, nil
+	case t.kind == tokenIdent:
+		if !p.isOperator("(") {
+			return &identNode{pos: t.pos, name: t.text}, nil
+		}
+		p.advance()
+		var args []exprNode
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !p.isOperator(",") {
+				break
+			}
+			p.advance()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return &callNode{pos: t.pos, name: t.text, args: args}, nil
+	case t.kind == tokenOperator && t.text == "(":
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case t.kind == tokenEOF:
+		return nil, &exprError{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return nil, &exprError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOperator(op) {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return &exprError{Pos: t.pos, Msg: fmt.Sprintf("expected %q before end of expression", op)}
+		}
+		return &exprError{Pos: t.pos, Msg: fmt.Sprintf("expected %q, found %q", op, t.text)}
+	}
+	p.advance()
+	return nil
+}
+
+// Evaluation
+
+var constants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0 means variadic
+	eval             func(args []float64) (float64, error)
+}
+
+var functions = map[string]function{
+	"sqrt": {1, 1, func(a []float64) (float64, error) {
+		if a[0] < 0 {
+			return 0, fmt.Errorf("square root of negative number %v", a[0])
+		}
+		return math.Sqrt(a[0]), nil
+	}},
+	"abs": {1, 1, func(a []float64) (float64, error) { return math.Abs(a[0]), nil }},
+	"min": {1, -1, func(a []float64) (float64, error) {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m, nil
+	}},
+	"max": {1, -1, func(a []float64) (float64, error) {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m, nil
+	}},
+	// log(x) is the natural logarithm, log(x, b) the logarithm in base b
+	"log": {1, 2, func(a []float64) (float64, error) {
+		if a[0] <= 0 {
+			return 0, fmt.Errorf("logarithm of non-positive number %v", a[0])
+		}
+		if len(a) == 1 {
+			return math.Log(a[0]), nil
+		}
+		if a[1] <= 0 || a[1] == 1 {
+			return 0, fmt.Errorf("invalid logarithm base %v", a[1])
+		}
+		return math.Log(a[0]) / math.Log(a[1]), nil
+	}},
+	"exp":   {1, 1, func(a []float64) (float64, error) { return math.Exp(a[0]), nil }},
+	"sin":   {1, 1, func(a []float64) (float64, error) { return math.Sin(a[0]), nil }},
+	"cos":   {1, 1, func(a []float64) (float64, error) { return math.Cos(a[0]), nil }},
+	"tan":   {1, 1, func(a []float64) (float64, error) { return math.Tan(a[0]), nil }},
+	"floor": {1, 1, func(a []float64) (float64, error) { return math.Floor(a[0]), nil }},
+	"ceil":  {1, 1, func(a []float64) (float64, error) { return math.Ceil(a[0]), nil }},
+	"round": {1, 1, func(a []float64) (float64, error) { return math.Round(a[0]), nil }},
+}
+
+// lookupFunc resolves identifiers that are not built-in constants.
+type lookupFunc func(name string) (float64, bool)
+
+// evaluate computes the value of a parsed expression. Identifiers are looked
+// up in the constants first, then in lookup when it is not nil.
+func evaluate(node exprNode, lookup lookupFunc) (float64, error) {
+	v, err := eval(node, lookup)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, &exprError{Pos: node.position(), Msg: "result is not a finite number"}
+	}
+	return v, nil
+}
+
+func eval(node exprNode, lookup lookupFunc) (float64, error) {
+	switch n := node.(type) {
+	case *numberNode:
+		v, err := strconv.ParseFloat(n.text, 64)
+		if err != nil {
+			return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf("invalid number %q", n.text)}
+		}
+		return v, nil
+	case *identNode:
+		if v, ok := constants[n.name]; ok {
+			return v, nil
+		}
+		if lookup != nil {
+			if v, ok := lookup(n.name); ok {
+				return v, nil
+			}
+		}
+		return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown name %q", n.name)}
+	case *unaryNode:
+		x, err := eval(n.x, lookup)
+		if err != nil {
+			return 0, err
+		}
+		if n.op == "-" {
+			return -x, nil
+		}
+		return x, nil
+	case *binaryNode:
+		l, err := eval(n.l, lookup)
+		if err != nil {
+			return 0, err
+		}
+		r, err := eval(n.r, lookup)
+		if err != nil {
+			return 0, err
+		}
+		switch n.op {
+		case "+":
+			return l + r, nil
+		case "-":
+			return l - r, nil
+		case "*":
+			return l * r, nil
+		case "/":
+			if r == 0 {
+				return 0, &exprError{Pos: n.pos, Msg: "division by zero"}
+			}
+			return l / r, nil
+		case "%":
+			if r == 0 {
+				return 0, &exprError{Pos: n.pos, Msg: "modulo by zero"}
+			}
+			return math.Mod(l, r), nil
+		case "^":
+			v := math.Pow(l, r)
+			if math.IsNaN(v) {
+				return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf("%v ^ %v is not a real number", l, r)}
+			}
+			return v, nil
+		}
+		return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown operator %q", n.op)}
+	case *callNode:
+		fn, ok := functions[n.name]
+		if !ok {
+			return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf("unknown function %q", n.name)}
+		}
+		if len(n.args) < fn.minArgs || (fn.maxArgs >= 0 && len(n.args) > fn.maxArgs) {
+			return 0, &exprError{Pos: n.pos, Msg: fmt.Sprintf(
+				"wrong number of arguments to %s: %d", n.name, len(n.args),
+			)}
+		}
+		args := make([]float64, len(n.args))
+		for i, arg := range n.args {
+			v, err := eval(arg, lookup)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		v, err := fn.eval(args)
+		if err != nil {
+			return 0, &exprError{Pos: n.pos, Msg: err.Error()}
+		}
+		return v, nil
+	}
+	return 0, &exprError{Pos: node.position(), Msg: "unknown expression"}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	tests := map[string]float64{
+		"1 + 2 * 3":                           7,
+		"(1 + 2) * 3":                         9,
+		"10 - 4 - 3":                          3,
+		"2 ^ 3 ^ 2":                           512,
+		"-2 ^ 2":                              -4,
+		"2 ^ -1":                              0.5,
+		"7 % 4 * 2":                           6,
+		"--3":                                 3,
+		"1e3 + .5":                            1000.5,
+		"2 * (3 + sqrt(16)) ^ 2 - max(1, pi)": 98 - math.Pi,
+		"log(8, 2)":                           3,
+		"min(4, -1, 2)":                       -1,
+	}
+	for src, want := range tests {
+		node, err := parseExpression(src)
+		if err != nil {
+			t.Errorf("parseExpression(%q) failed: %v", src, err)
+			continue
+		}
+		got, err := evaluate(node, nil)
+		if err != nil || math.Abs(got-want) > 1e-12 {
+			t.Errorf("evaluate(%q) = %v, %v, want %v", src, got, err, want)
+		}
+	}
+}
+
+func TestEvaluateLookup(t *testing.T) {
+	vars := map[string]float64{"x": 2, "µ": 3, "tête_1": 4}
+	lookup := func(name string) (float64, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	node, err := parseExpression("x * µ + tête_1")
+	if err != nil {
+		t.Fatalf("parseExpression() failed: %v", err)
+	}
+	if got, err := evaluate(node, lookup); err != nil || got != 10 {
+		t.Errorf("evaluate() = %v, %v, want 10", got, err)
+	}
+}
+
+func TestExpressionErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+		msg string
+	}{
+		{"1 +", 3, "unexpected end"},
+		{"(1 + 2", 6, `expected ")"`},
+		{"1 + 2)", 5, `unexpected ")"`},
+		{"2 $ 3", 2, "unexpected character '$'"},
+		// Multi-byte characters are reported whole, at their first byte
+		{"1 + € 2", 4, "unexpected character '€'"},
+		{"µ + ∑", 5, "unexpected character '∑'"},
+		{"1 + \xff", 4, "invalid UTF-8"},
+		// Only ASCII digits make numbers
+		{"1 + ٣", 4, "unexpected character"},
+		{"1 / (2 - 2)", 2, "division by zero"},
+		{"3 + foo", 4, `unknown name "foo"`},
+		{"1 + bar(2)", 4, `unknown function "bar"`},
+		{"sqrt(1, 2)", 0, "wrong number of arguments"},
+		{"sqrt(-4)", 0, "square root of negative number"},
+		{"(-8) ^ 0.5", 5, "is not a real number"},
+		{"10 ^ 400", 3, "not a finite number"},
+		{"1.2.3", 0, "invalid number"},
+	}
+	for _, tt := range tests {
+		node, err := parseExpression(tt.src)
+		if err == nil {
+			_, err = evaluate(node, nil)
+		}
+		e, ok := err.(*exprError)
+		if !ok || e.Pos != tt.pos || !strings.Contains(e.Msg, tt.msg) {
+			t.Errorf("%q: got error %v, want %q at position %d", tt.src, err, tt.msg, tt.pos)
+		}
+	}
+}
+
+func TestExpressionLimits(t *testing.T) {
+	tests := []string{
+		strings.Repeat("1+", maxExpressionLength/2) + "1",
+		strings.Repeat("(", maxNestingDepth+1) + "1" + strings.Repeat(")", maxNestingDepth+1),
+		strings.Repeat("-", maxNestingDepth+1) + "1",
+	}
+	for _, src := range tests {
+		if _, err := parseExpression(src); err == nil {
+			t.Errorf("parseExpression(%.20q...) succeeded, want a limit error", src)
+		} else if _, ok := err.(*limitError); !ok {
+			t.Errorf("parseExpression(%.20q...) = %v, want a limit error", src, err)
+		}
+	}
+
+	atLimit := strings.Repeat("(", maxNestingDepth-1) + "1" + strings.Repeat(")", maxNestingDepth-1)
+	if _, err := parseExpression(atLimit); err != nil {
+		t.Errorf("parseExpression() nested %d levels failed: %v", maxNestingDepth-1, err)
+	}
+}
+
+func TestParseStatement(t *testing.T) {
+	name, node, err := parseStatement("total = 2 * 3")
+	if err != nil || name != "total" {
+		t.Fatalf("parseStatement() = %q, %v, want an assignment to total", name, err)
+	}
+	if got, _ := evaluate(node, nil); got != 6 {
+		t.Errorf("value of the assignment = %v, want 6", got)
+	}
+	if name, _, err := parseStatement("2 * 3"); err != nil || name != "" {
+		t.Errorf("parseStatement() of a bare expression = %q, %v", name, err)
+	}
+	for _, src := range []string{"pi = 3", "sqrt = 2"} {
+		if _, _, err := parseStatement(src); err == nil {
+			t.Errorf("parseStatement(%q) succeeded, want an error", src)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"time"
+)
+
+const (
+	// Numbers longer than this are rejected, Pollard's rho would not finish on them anyway.
+	maxFactorDigits = 200
+
+	// Time the server spends factoring one number, shorter ones can still be
+	// too hard for Pollard's rho.
+	maxFactorTime = 10 * time.Second
+
+	// Rounds of Miller-Rabin on top of the Baillie-PSW test done by ProbablyPrime.
+	// ProbablyPrime is exact for numbers below 2^64.
+	millerRabinRounds = 20
+
+	trialDivisionLimit = 1000
+)
+
+var (
+	bigOne      = big.NewInt(1)
+	smallPrimes = sieve(trialDivisionLimit)
+)
+
+// sieve returns the primes up to limit.
+func sieve(limit int) []int64 {
+	composite := make([]bool, limit+1)
+	var primes []int64
+	for i := 2; i <= limit; i++ {
+		if composite[i] {
+			continue
+		}
+		primes = append(primes, int64(i))
+		for j := i * i; j <= limit; j += i {
+			composite[j] = true
+		}
+	}
+	return primes
+}
+
+// factorize calls emit for every prime factor of n > 0 with its multiplicity.
+// Small factors are emitted in increasing order, larger ones in the order
+// they are found. It stops with ctx.Err() once ctx is cancelled, returning
+// the part of n left unfactored, which is 1 when it finishes.
+func factorize(ctx context.Context, n *big.Int, emit func(prime *big.Int, multiplicity int) error) (*big.Int, error) {
+	remaining := new(big.Int).Set(n)
+	q, r := new(big.Int), new(big.Int)
+
+	for _, p := range smallPrimes {
+		prime := big.NewInt(p)
+		multiplicity := 0
+		for {
+			q.QuoRem(remaining, prime, r)
+			if r.Sign() != 0 {
+				break
+			}
+			remaining.Set(q)
+			multiplicity++
+		}
+		if multiplicity > 0 {
+			if err := emit(prime, multiplicity); err != nil {
+				return remaining, err
+			}
+		}
+		if remaining.Cmp(bigOne) == 0 {
+			return remaining, nil
+		}
+	}
+
+	for remaining.Cmp(bigOne) > 0 {
+		if err := ctx.Err(); err != nil {
+			return remaining, err
+		}
+		prime, err := findPrimeFactor(ctx, remaining)
+		if err != nil {
+			return remaining, err
+		}
+		multiplicity := 0
+		for {
+			q.QuoRem(remaining, prime, r)
+			if r.Sign() != 0 {
+				break
+			}
+			remaining.Set(q)
+			multiplicity++
+		}
+		if err := emit(prime, multiplicity); err != nil {
+			return remaining, err
+		}
+	}
+	return remaining, nil
+}
+
+// findPrimeFactor returns a prime factor of n, which has no factors below
+// trialDivisionLimit. The result never aliases n.
+func findPrimeFactor(ctx context.Context, n *big.Int) (*big.Int, error) {
+	n = new(big.Int).Set(n)
+	for !n.ProbablyPrime(millerRabinRounds) {
+		d, err := pollardRho(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		// Keep splitting the smaller part
+		other := new(big.Int).Quo(n, d)
+		if other.Cmp(d) < 0 {
+			d = other
+		}
+		n = d
+	}
+	return n, nil
+}
+
+// pollardRho returns a non-trivial factor of the odd composite n using
+// Brent's variant of Pollard's rho algorithm.
+func pollardRho(ctx context.Context, n *big.Int) (*big.Int, error) {
+	const batch = 128
+
+	x, y, ys := new(big.Int), new(big.Int), new(big.Int)
+	q, g, diff := new(big.Int), new(big.Int), new(big.Int)
+
+	for c := int64(1); ; c++ {
+		constant := big.NewInt(c)
+		next := func(v *big.Int) {
+			v.Mul(v, v)
+			v.Add(v, constant)
+			v.Mod(v, n)
+		}
+
+		y.SetInt64(2)
+		q.SetInt64(1)
+		g.SetInt64(1)
+		for r := 1; g.Cmp(bigOne) == 0; r *= 2 {
+			x.Set(y)
+			for i := 0; i < r; i++ {
+				if i%batch == 0 {
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+				}
+				next(y)
+			}
+			for k := 0; k < r && g.Cmp(bigOne) == 0; k += batch {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				ys.Set(y)
+				for i := 0; i < batch && i < r-k; i++ {
+					next(y)
+					diff.Sub(x, y)
+					q.Mul(q, diff.Abs(diff))
+					q.Mod(q, n)
+				}
+				g.GCD(nil, nil, q, n)
+			}
+		}
+
+		// The batch overshot, step back one value at a time
+		if g.Cmp(n) == 0 {
+			for {
+				next(ys)
+				diff.Sub(x, ys)
+				g.GCD(nil, nil, diff.Abs(diff), n)
+				if g.Cmp(bigOne) > 0 {
+					break
+				}
+			}
+		}
+		if g.Cmp(n) != 0 {
+			return new(big.Int).Set(g), nil
+		}
+		// The cycle did not split n, retry with another polynomial
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSieve(t *testing.T) {
+	want := []int64{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}
+	got := sieve(30)
+	if len(got) != len(want) {
+		t.Fatalf("sieve(30) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sieve(30) = %v, want %v", got, want)
+		}
+	}
+	if got := sieve(1); len(got) != 0 {
+		t.Errorf("sieve(1) = %v, want no primes", got)
+	}
+	if got := len(sieve(trialDivisionLimit)); got != 168 {
+		t.Errorf("sieve(%d) has %d primes, want 168", trialDivisionLimit, got)
+	}
+}
+
+func TestFactorize(t *testing.T) {
+	tests := []struct {
+		n    string
+		want map[string]int
+	}{
+		{"1", map[string]int{}},
+		{"2", map[string]int{"2": 1}},
+		{"120", map[string]int{"2": 3, "3": 1, "5": 1}},
+		{"124538982", map[string]int{"2": 1, "3": 1, "617": 1, "33641": 1}},
+		{"2305843009213693951", map[string]int{"2305843009213693951": 1}},
+		// 100000000003^2 * 700000000031, larger than an int64
+		{"7000000000730000000024900000000279", map[string]int{"100000000003": 2, "700000000031": 1}},
+		// Carmichael number, fools Fermat tests
+		{"561", map[string]int{"3": 1, "11": 1, "17": 1}},
+		// Square of a prime above the trial division limit
+		{"1000006000009", map[string]int{"1000003": 2}},
+	}
+	for _, tt := range tests {
+		n, _ := new(big.Int).SetString(tt.n, 10)
+		got := map[string]int{}
+		product := big.NewInt(1)
+		unfactored, err := factorize(context.Background(), n, func(prime *big.Int, multiplicity int) error {
+			if isPrime, _ := isPrime(prime); !isPrime {
+				t.Errorf("factorize(%v) emitted the composite %v", n, prime)
+			}
+			got[prime.String()] += multiplicity
+			for i := 0; i < multiplicity; i++ {
+				product.Mul(product, prime)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("factorize(%v) failed: %v", n, err)
+			continue
+		}
+		if unfactored.Cmp(bigOne) != 0 {
+			t.Errorf("factorize(%v) left %v unfactored", n, unfactored)
+		}
+		if product.Cmp(n) != 0 {
+			t.Errorf("factorize(%v) emitted %v, whose product is %v", n, got, product)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("factorize(%v) = %v, want %v", n, got, tt.want)
+		}
+	}
+}
+
+func TestFactorizeStopsOnDeadline(t *testing.T) {
+	// Product of two 100-bit primes, far beyond Pollard's rho in a millisecond
+	p, _ := new(big.Int).SetString("1267650600228229401496703205653", 10)
+	q, _ := new(big.Int).SetString("1267650600228229401496703205707", 10)
+	n := new(big.Int).Mul(p, q)
+	n.Mul(n, big.NewInt(12))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	var emitted []string
+	unfactored, err := factorize(ctx, n, func(prime *big.Int, multiplicity int) error {
+		emitted = append(emitted, prime.String())
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("factorize() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(emitted) != 2 || emitted[0] != "2" || emitted[1] != "3" {
+		t.Errorf("factorize() emitted %v before the deadline, want [2 3]", emitted)
+	}
+	if want := new(big.Int).Mul(p, q); unfactored.Cmp(want) != 0 {
+		t.Errorf("factorize() left %v unfactored, want %v", unfactored, want)
+	}
+}
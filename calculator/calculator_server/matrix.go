@@ -0,0 +1,258 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wiliamhw/golang-grpc-example/calculator/calculatorpb"
+	"math"
+)
+
+// maxMatrixDimension bounds the rows and columns of every matrix, keeping the
+// cubic operations below a few tens of millions of multiplications.
+const maxMatrixDimension = 256
+
+var errSingularMatrix = errors.New("matrix is singular")
+
+// matrix is a dense row-major matrix.
+type matrix struct {
+	rows, cols int
+	data       []float64
+}
+
+func newMatrix(rows, cols int) *matrix {
+	return &matrix{rows: rows, cols: cols, data: make([]float64, rows*cols)}
+}
+
+func (m *matrix) at(i, j int) float64 { return m.data[i*m.cols+j] }
+
+func (m *matrix) set(i, j int, v float64) { m.data[i*m.cols+j] = v }
+
+// matrixFromProto validates the dimensions and values of pb.
+func matrixFromProto(name string, pb *calculatorpb.Matrix) (*matrix, error) {
+	if pb == nil {
+		return nil, fmt.Errorf("matrix %v is missing", name)
+	}
+	rows, cols := int(pb.GetRows()), int(pb.GetColumns())
+	if rows < 1 || cols < 1 || rows > maxMatrixDimension || cols > maxMatrixDimension {
+		return nil, fmt.Errorf("matrix %v is %vx%v, rows and columns must be between 1 and %v", name, rows, cols, maxMatrixDimension)
+	}
+	if len(pb.GetValues()) != rows*cols {
+		return nil, fmt.Errorf("matrix %v is %vx%v but has %v values", name, rows, cols, len(pb.GetValues()))
+	}
+	if err := checkFinite(name, pb.GetValues()); err != nil {
+		return nil, err
+	}
+	return &matrix{rows: rows, cols: cols, data: append([]float64(nil), pb.GetValues()...)}, nil
+}
+
+// vectorFromProto validates the length and values of pb.
+func vectorFromProto(name string, pb *calculatorpb.Vector) ([]float64, error) {
+	values := pb.GetValues()
+	if len(values) < 1 || len(values) > maxMatrixDimension {
+		return nil, fmt.Errorf("vector %v has %v values, it must have between 1 and %v", name, len(values), maxMatrixDimension)
+	}
+	if err := checkFinite(name, values); err != nil {
+		return nil, err
+	}
+	return append([]float64(nil), values...), nil
+}
+
+func checkFinite(name string, values []float64) error {
+	for i, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%v has a non-finite value at index %v", name, i)
+		}
+	}
+	return nil
+}
+
+func (m *matrix) toProto() *calculatorpb.Matrix {
+	return &calculatorpb.Matrix{Rows: int32(m.rows), Columns: int32(m.cols), Values: m.data}
+}
+
+func (m *matrix) square() error {
+	if m.rows != m.cols {
+		return fmt.Errorf("matrix is %vx%v, it must be square", m.rows, m.cols)
+	}
+	return nil
+}
+
+func addMatrices(a, b *matrix) (*matrix, error) {
+	if a.rows != b.rows || a.cols != b.cols {
+		return nil, fmt.Errorf("cannot add a %vx%v matrix to a %vx%v matrix", a.rows, a.cols, b.rows, b.cols)
+	}
+	result := newMatrix(a.rows, a.cols)
+	for i := range a.data {
+		result.data[i] = a.data[i] + b.data[i]
+	}
+	return result, nil
+}
+
+func multiplyMatrices(a, b *matrix) (*matrix, error) {
+	if a.cols != b.rows {
+		return nil, fmt.Errorf("cannot multiply a %vx%v matrix by a %vx%v matrix", a.rows, a.cols, b.rows, b.cols)
+	}
+	result := newMatrix(a.rows, b.cols)
+	// i-k-j order walks both b and the result row by row
+	for i := 0; i < a.rows; i++ {
+		for k := 0; k < a.cols; k++ {
+			x := a.at(i, k)
+			if x == 0 {
+				continue
+			}
+			for j := 0; j < b.cols; j++ {
+				result.data[i*result.cols+j] += x * b.at(k, j)
+			}
+		}
+	}
+	return result, nil
+}
+
+func transpose(m *matrix) *matrix {
+	result := newMatrix(m.cols, m.rows)
+	for i := 0; i < m.rows; i++ {
+		for j := 0; j < m.cols; j++ {
+			result.set(j, i, m.at(i, j))
+		}
+	}
+	return result
+}
+
+// luDecomposition holds PA = LU for a square matrix, L and U sharing one
+// matrix with the unit diagonal of L left implicit.
+type luDecomposition struct {
+	lu       *matrix
+	pivot    []int
+	sign     float64 // determinant of P
+	singular bool
+}
+
+// decompose factors m with Gaussian elimination and scaled partial pivoting.
+// Pivots are compared to the largest value of their original row, so that rows
+// of very different magnitudes do not hide each other, and a pivot negligible
+// next to it marks m as singular.
+func decompose(m *matrix) *luDecomposition {
+	n := m.rows
+	lu := &matrix{rows: n, cols: n, data: append([]float64(nil), m.data...)}
+	d := &luDecomposition{lu: lu, pivot: make([]int, n), sign: 1}
+
+	rowScale := make([]float64, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			rowScale[i] = math.Max(rowScale[i], math.Abs(m.at(i, j)))
+		}
+		if rowScale[i] == 0 {
+			d.singular = true
+			return d
+		}
+	}
+	tolerance := float64(n) * 1e-12
+
+	for i := range d.pivot {
+		d.pivot[i] = i
+	}
+	for k := 0; k < n; k++ {
+		p := k
+		for i := k + 1; i < n; i++ {
+			if math.Abs(lu.at(i, k))/rowScale[i] > math.Abs(lu.at(p, k))/rowScale[p] {
+				p = i
+			}
+		}
+		if math.Abs(lu.at(p, k))/rowScale[p] <= tolerance {
+			d.singular = true
+			return d
+		}
+		if p != k {
+			for j := 0; j < n; j++ {
+				x := lu.at(k, j)
+				lu.set(k, j, lu.at(p, j))
+				lu.set(p, j, x)
+			}
+			d.pivot[k], d.pivot[p] = d.pivot[p], d.pivot[k]
+			rowScale[k], rowScale[p] = rowScale[p], rowScale[k]
+			d.sign = -d.sign
+		}
+		for i := k + 1; i < n; i++ {
+			factor := lu.at(i, k) / lu.at(k, k)
+			lu.set(i, k, factor)
+			for j := k + 1; j < n; j++ {
+				lu.set(i, j, lu.at(i, j)-factor*lu.at(k, j))
+			}
+		}
+	}
+	return d
+}
+
+func (d *luDecomposition) determinant() float64 {
+	if d.singular {
+		return 0
+	}
+	det := d.sign
+	for i := 0; i < d.lu.rows; i++ {
+		det *= d.lu.at(i, i)
+	}
+	return det
+}
+
+// solve returns x such that A x = b.
+func (d *luDecomposition) solve(b []float64) []float64 {
+	n := d.lu.rows
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		sum := b[d.pivot[i]]
+		for j := 0; j < i; j++ {
+			sum -= d.lu.at(i, j) * x[j]
+		}
+		x[i] = sum
+	}
+	for i := n - 1; i >= 0; i-- {
+		sum := x[i]
+		for j := i + 1; j < n; j++ {
+			sum -= d.lu.at(i, j) * x[j]
+		}
+		x[i] = sum / d.lu.at(i, i)
+	}
+	return x
+}
+
+func determinant(m *matrix) (float64, error) {
+	if err := m.square(); err != nil {
+		return 0, err
+	}
+	return decompose(m).determinant(), nil
+}
+
+func inverse(m *matrix) (*matrix, error) {
+	if err := m.square(); err != nil {
+		return nil, err
+	}
+	d := decompose(m)
+	if d.singular {
+		return nil, errSingularMatrix
+	}
+	n := m.rows
+	result := newMatrix(n, n)
+	unit := make([]float64, n)
+	for j := 0; j < n; j++ {
+		unit[j] = 1
+		for i, v := range d.solve(unit) {
+			result.set(i, j, v)
+		}
+		unit[j] = 0
+	}
+	return result, nil
+}
+
+func solveLinearSystem(a *matrix, b []float64) ([]float64, error) {
+	if err := a.square(); err != nil {
+		return nil, err
+	}
+	if len(b) != a.rows {
+		return nil, fmt.Errorf("cannot solve a %vx%v system with a vector of %v values", a.rows, a.cols, len(b))
+	}
+	d := decompose(a)
+	if d.singular {
+		return nil, errSingularMatrix
+	}
+	return d.solve(b), nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func newMatrixOf(rows, cols int, values ...float64) *matrix {
+	return &matrix{rows: rows, cols: cols, data: values}
+}
+
+func closeTo(a, b []float64, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > tolerance*math.Max(1, math.Abs(b[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMultiplyAndTranspose(t *testing.T) {
+	a := newMatrixOf(2, 3, 1, 2, 3, 4, 5, 6)
+	b := newMatrixOf(3, 2, 7, 8, 9, 10, 11, 12)
+	product, err := multiplyMatrices(a, b)
+	if err != nil {
+		t.Fatalf("multiplyMatrices() failed: %v", err)
+	}
+	if want := []float64{58, 64, 139, 154}; !closeTo(product.data, want, 0) {
+		t.Errorf("multiplyMatrices() = %v, want %v", product.data, want)
+	}
+	if _, err := multiplyMatrices(a, a); err == nil {
+		t.Errorf("multiplyMatrices() of a 2x3 by a 2x3 matrix succeeded")
+	}
+
+	at := transpose(a)
+	if at.rows != 3 || at.cols != 2 || !closeTo(at.data, []float64{1, 4, 2, 5, 3, 6}, 0) {
+		t.Errorf("transpose() = %+v", at)
+	}
+
+	sum, err := addMatrices(a, a)
+	if err != nil || !closeTo(sum.data, []float64{2, 4, 6, 8, 10, 12}, 0) {
+		t.Errorf("addMatrices() = %v, %v", sum, err)
+	}
+	if _, err := addMatrices(a, b); err == nil {
+		t.Errorf("addMatrices() of a 2x3 and a 3x2 matrix succeeded")
+	}
+}
+
+func TestDeterminant(t *testing.T) {
+	tests := []struct {
+		m    *matrix
+		want float64
+	}{
+		{newMatrixOf(1, 1, -3), -3},
+		{newMatrixOf(2, 2, 1, 2, 3, 4), -2},
+		// Needs a row swap, which flips the sign
+		{newMatrixOf(2, 2, 0, 1, 1, 0), -1},
+		{newMatrixOf(3, 3, 2, 0, 1, 1, 3, 2, 1, 1, 2), 6},
+		{newMatrixOf(2, 2, 1, 2, 2, 4), 0},
+		{newMatrixOf(2, 2, 0, 0, 1, 1), 0},
+	}
+	for _, tt := range tests {
+		got, err := determinant(tt.m)
+		if err != nil || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("determinant(%v) = %v, %v, want %v", tt.m.data, got, err, tt.want)
+		}
+	}
+	if _, err := determinant(newMatrixOf(1, 2, 1, 2)); err == nil {
+		t.Errorf("determinant() of a 1x2 matrix succeeded")
+	}
+}
+
+func TestInverse(t *testing.T) {
+	m := newMatrixOf(3, 3, 4, 7, 2, 3, 6, 1, 2, 5, 3)
+	inv, err := inverse(m)
+	if err != nil {
+		t.Fatalf("inverse() failed: %v", err)
+	}
+	identity, _ := multiplyMatrices(m, inv)
+	if want := []float64{1, 0, 0, 0, 1, 0, 0, 0, 1}; !closeTo(identity.data, want, 1e-12) {
+		t.Errorf("m * inverse(m) = %v, want the identity", identity.data)
+	}
+
+	if _, err := inverse(newMatrixOf(2, 2, 1, 2, 2, 4)); err != errSingularMatrix {
+		t.Errorf("inverse() of a singular matrix = %v, want %v", err, errSingularMatrix)
+	}
+}
+
+func TestSingularityIsScaleInvariant(t *testing.T) {
+	tests := []struct {
+		m        *matrix
+		singular bool
+	}{
+		// Rows of very different magnitudes, each perfectly conditioned
+		{newMatrixOf(2, 2, 1e-20, 0, 0, 1), false},
+		{newMatrixOf(2, 2, 1e-20, 1e-20, 1, 2), false},
+		{newMatrixOf(3, 3, 1e15, 0, 0, 0, 1, 0, 0, 0, 1e-15), false},
+		// Proportional rows are singular at any scale
+		{newMatrixOf(2, 2, 1e-20, 2e-20, 1, 2), true},
+		{newMatrixOf(2, 2, 1e20, 2e20, 1, 2), true},
+		{newMatrixOf(3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 9), true},
+	}
+	for _, tt := range tests {
+		if got := decompose(tt.m).singular; got != tt.singular {
+			t.Errorf("decompose(%v).singular = %v, want %v", tt.m.data, got, tt.singular)
+		}
+	}
+}
+
+func TestSolveLinearSystem(t *testing.T) {
+	// 2x + y - z = 8, -3x - y + 2z = -11, -2x + y + 2z = -3
+	a := newMatrixOf(3, 3, 2, 1, -1, -3, -1, 2, -2, 1, 2)
+	x, err := solveLinearSystem(a, []float64{8, -11, -3})
+	if err != nil {
+		t.Fatalf("solveLinearSystem() failed: %v", err)
+	}
+	if want := []float64{2, 3, -1}; !closeTo(x, want, 1e-12) {
+		t.Errorf("solveLinearSystem() = %v, want %v", x, want)
+	}
+
+	if _, err := solveLinearSystem(a, []float64{1, 2}); err == nil {
+		t.Errorf("solveLinearSystem() with a vector of the wrong length succeeded")
+	}
+	if _, err := solveLinearSystem(newMatrixOf(2, 2, 1, 1, 1, 1), []float64{1, 2}); err != errSingularMatrix {
+		t.Errorf("solveLinearSystem() of a singular system = %v, want %v", err, errSingularMatrix)
+	}
+}
+
+func TestSolveRandomSystems(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 5, 50} {
+		a := newMatrix(n, n)
+		for i := range a.data {
+			a.data[i] = r.NormFloat64()
+		}
+		want := make([]float64, n)
+		for i := range want {
+			want[i] = r.NormFloat64()
+		}
+		b := make([]float64, n)
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				b[i] += a.at(i, j) * want[j]
+			}
+		}
+		x, err := solveLinearSystem(a, b)
+		if err != nil {
+			t.Fatalf("solveLinearSystem() of a random %dx%d system failed: %v", n, n, err)
+		}
+		if !closeTo(x, want, 1e-8) {
+			t.Errorf("solveLinearSystem() of a random %dx%d system = %v, want %v", n, n, x, want)
+		}
+	}
+}
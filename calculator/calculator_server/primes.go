@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+const (
+	maxPrimeDigits = 300
+
+	// GeneratePrimes keeps the base primes up to sqrt(to) in memory, which
+	// this bound keeps around fifteen megabytes per stream.
+	maxGeneratePrimesTo = 100_000_000_000_000
+	primeSegmentSize    = 1 << 16
+)
+
+// primeCertainty is the lower bound of the probability that a number reported
+// as prime by ProbablyPrime is prime, each Miller-Rabin round has an error of at most 1/4.
+var primeCertainty = 1 - math.Pow(4, -millerRabinRounds)
+
+// parseBigNumber returns decimal as an integer when it is set, number otherwise.
+func parseBigNumber(number int64, decimal string, maxDigits int) (*big.Int, error) {
+	n := big.NewInt(number)
+	if decimal == "" {
+		return n, nil
+	}
+	if len(decimal) > maxDigits {
+		return nil, fmt.Errorf("number has more than %d digits", maxDigits)
+	}
+	if _, ok := n.SetString(decimal, 10); !ok {
+		return nil, fmt.Errorf("invalid number %q", decimal)
+	}
+	return n, nil
+}
+
+// isPrime reports whether n is prime and whether the answer is certain.
+// ProbablyPrime is exact for numbers below 2^64.
+func isPrime(n *big.Int) (prime bool, deterministic bool) {
+	deterministic = n.BitLen() <= 64
+	if n.Sign() <= 0 {
+		return false, true
+	}
+	return n.ProbablyPrime(millerRabinRounds), deterministic
+}
+
+// nextPrime returns the smallest prime greater than n.
+func nextPrime(ctx context.Context, n *big.Int) (*big.Int, error) {
+	two := big.NewInt(2)
+	if n.Cmp(two) < 0 {
+		return two, nil
+	}
+
+	candidate := new(big.Int).Add(n, bigOne)
+	if candidate.Bit(0) == 0 {
+		candidate.Add(candidate, bigOne)
+	}
+	mod := new(big.Int)
+	for i := 0; ; i++ {
+		if i%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if !hasSmallFactor(candidate, mod) && candidate.ProbablyPrime(millerRabinRounds) {
+			return candidate, nil
+		}
+		candidate.Add(candidate, two)
+	}
+}
+
+// hasSmallFactor cheaply rules out most composites before the primality test.
+func hasSmallFactor(n *big.Int, mod *big.Int) bool {
+	for _, p := range smallPrimes {
+		prime := big.NewInt(p)
+		if n.Cmp(prime) == 0 {
+			return false
+		}
+		if mod.Mod(n, prime).Sign() == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// generatePrimes calls emit with the primes in [from, to] in increasing
+// order, one batch per sieve segment, reusing the batch once emit returns.
+// Memory is bounded by the base primes up to sqrt(to) and a single segment.
+func generatePrimes(ctx context.Context, from, to int64, emit func(primes []int64) error) error {
+	if from < 2 {
+		from = 2
+	}
+	if to < from {
+		return nil
+	}
+	basePrimes := sieve(int(math.Sqrt(float64(to))) + 1)
+	composite := make([]bool, primeSegmentSize)
+	batch := make([]int64, 0, primeSegmentSize/4)
+
+	for lo := from; lo <= to; lo += primeSegmentSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hi := lo + primeSegmentSize - 1
+		if hi > to || hi < lo {
+			hi = to
+		}
+
+		for i := range composite {
+			composite[i] = false
+		}
+		for _, p := range basePrimes {
+			if p*p > hi {
+				break
+			}
+			start := (lo + p - 1) / p * p
+			if start < p*p {
+				start = p * p
+			}
+			for m := start; m <= hi; m += p {
+				composite[m-lo] = true
+			}
+		}
+
+		batch = batch[:0]
+		for n := lo; n <= hi; n++ {
+			if !composite[n-lo] {
+				batch = append(batch, n)
+			}
+		}
+		if len(batch) > 0 {
+			if err := emit(batch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"testing"
+)
+
+func TestIsPrime(t *testing.T) {
+	tests := []struct {
+		n             string
+		prime         bool
+		deterministic bool
+	}{
+		{"-7", false, true},
+		{"0", false, true},
+		{"1", false, true},
+		{"2", true, true},
+		{"561", false, true},
+		{"18446744073709551557", true, true}, // largest prime below 2^64
+		{"18446744073709551617", false, false},
+		{"170141183460469231731687303715884105727", true, false}, // 2^127-1
+	}
+	for _, tt := range tests {
+		n, _ := new(big.Int).SetString(tt.n, 10)
+		prime, deterministic := isPrime(n)
+		if prime != tt.prime || deterministic != tt.deterministic {
+			t.Errorf("isPrime(%v) = %v, %v, want %v, %v", n, prime, deterministic, tt.prime, tt.deterministic)
+		}
+	}
+}
+
+func TestNextPrime(t *testing.T) {
+	tests := map[string]string{
+		"-5":                   "2",
+		"1":                    "2",
+		"2":                    "3",
+		"13":                   "17",
+		"1000":                 "1009",
+		"18446744073709551557": "18446744073709551629",
+	}
+	for n, want := range tests {
+		x, _ := new(big.Int).SetString(n, 10)
+		got, err := nextPrime(context.Background(), x)
+		if err != nil {
+			t.Errorf("nextPrime(%v) failed: %v", n, err)
+			continue
+		}
+		if got.String() != want {
+			t.Errorf("nextPrime(%v) = %v, want %v", n, got, want)
+		}
+	}
+}
+
+func TestParseBigNumber(t *testing.T) {
+	if n, err := parseBigNumber(42, "", 10); err != nil || n.Int64() != 42 {
+		t.Errorf("parseBigNumber(42, \"\") = %v, %v, want 42", n, err)
+	}
+	if n, err := parseBigNumber(42, "12345678901234567890", 20); err != nil || n.String() != "12345678901234567890" {
+		t.Errorf("parseBigNumber() = %v, %v, want the decimal number", n, err)
+	}
+	for _, decimal := range []string{"12a", "1.5", "12345678901"} {
+		if _, err := parseBigNumber(0, decimal, 10); err == nil {
+			t.Errorf("parseBigNumber(%q) succeeded, want an error", decimal)
+		}
+	}
+}
+
+// generateAll collects the primes of generatePrimes.
+func generateAll(t *testing.T, from, to int64) []int64 {
+	var primes []int64
+	err := generatePrimes(context.Background(), from, to, func(batch []int64) error {
+		primes = append(primes, batch...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("generatePrimes(%d, %d) failed: %v", from, to, err)
+	}
+	return primes
+}
+
+func TestGeneratePrimesMatchesSieve(t *testing.T) {
+	// Crosses several segments
+	const limit = 3*primeSegmentSize + 123
+	want := sieve(limit)
+	got := generateAll(t, 0, limit)
+	if len(got) != len(want) {
+		t.Fatalf("generatePrimes(0, %d) returned %d primes, want %d", limit, len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("prime %d is %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestGeneratePrimesRanges(t *testing.T) {
+	tests := []struct {
+		from, to int64
+		want     []int64
+	}{
+		{10, 30, []int64{11, 13, 17, 19, 23, 29}},
+		{-10, 5, []int64{2, 3, 5}},
+		{7, 7, []int64{7}},
+		{8, 10, nil},
+		{30, 10, nil},
+		{1_000_000_000, 1_000_000_100, []int64{1_000_000_007, 1_000_000_009, 1_000_000_021, 1_000_000_033, 1_000_000_087, 1_000_000_093, 1_000_000_097}},
+	}
+	for _, tt := range tests {
+		got := generateAll(t, tt.from, tt.to)
+		if len(got) != len(tt.want) {
+			t.Errorf("generatePrimes(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("generatePrimes(%d, %d) = %v, want %v", tt.from, tt.to, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestGeneratePrimesStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := 0
+	err := generatePrimes(ctx, 2, maxGeneratePrimesTo, func([]int64) error {
+		batches++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Errorf("generatePrimes() = %v, want %v", err, context.Canceled)
+	}
+	if batches != 1 {
+		t.Errorf("generatePrimes() emitted %d batches after cancel, want 1", batches)
+	}
+}
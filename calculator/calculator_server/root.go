@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxRootPrecision is the largest number of significant digits a Root RPC may request.
+const maxRootPrecision = 1000
+
+// digitsToBits converts significant decimal digits to a big.Float precision with a few guard bits.
+func digitsToBits(digits int) uint {
+	return uint(math.Ceil(float64(digits)*math.Log2(10))) + 16
+}
+
+// principalRoot returns the principal complex n-th root of a negative x:
+// |x|^(1/n) * (cos(pi/n) + i*sin(pi/n)).
+func principalRoot(x float64, n int) (re, im float64) {
+	if n == 2 {
+		return 0, math.Sqrt(-x)
+	}
+	magnitude := math.Pow(-x, 1/float64(n))
+	angle := math.Pi / float64(n)
+	return magnitude * math.Cos(angle), magnitude * math.Sin(angle)
+}
+
+// realRoot returns the real n-th root of x, which must not be negative for an even n.
+func realRoot(x float64, n int) float64 {
+	switch {
+	case n == 2:
+		return math.Sqrt(x)
+	case n == 3:
+		return math.Cbrt(x)
+	case x < 0:
+		return -math.Pow(-x, 1/float64(n))
+	}
+	return math.Pow(x, 1/float64(n))
+}
+
+// nthRootBig computes the n-th root of a >= 0 with Newton's method, starting
+// from the float64 estimate so only a few iterations are needed.
+func nthRootBig(a *big.Float, n int, prec uint) *big.Float {
+	if a.Sign() == 0 {
+		return new(big.Float).SetPrec(prec)
+	}
+	if n == 2 {
+		return new(big.Float).SetPrec(prec).Sqrt(a)
+	}
+
+	estimate, _ := a.Float64()
+	y := new(big.Float).SetPrec(prec).SetFloat64(math.Pow(estimate, 1/float64(n)))
+	bn := new(big.Float).SetPrec(prec).SetInt64(int64(n))
+	bn1 := new(big.Float).SetPrec(prec).SetInt64(int64(n - 1))
+	for i := 0; i < 100; i++ {
+		// y = ((n-1)*y + a/y^(n-1)) / n
+		power := powBig(y, n-1, prec)
+		next := new(big.Float).SetPrec(prec).Quo(a, power)
+		next.Add(next, new(big.Float).SetPrec(prec).Mul(bn1, y))
+		next.Quo(next, bn)
+		if next.Cmp(y) == 0 {
+			break
+		}
+		y = next
+	}
+	return y
+}
+
+func powBig(x *big.Float, n int, prec uint) *big.Float {
+	result := new(big.Float).SetPrec(prec).SetInt64(1)
+	square := new(big.Float).SetPrec(prec).Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result.Mul(result, square)
+		}
+		square.Mul(square, square)
+	}
+	return result
+}
+
+// piBig computes pi with Machin's formula: pi = 16*atan(1/5) - 4*atan(1/239).
+func piBig(prec uint) *big.Float {
+	pi := atanInverseBig(5, prec)
+	pi.Mul(pi, big.NewFloat(16).SetPrec(prec))
+	t := atanInverseBig(239, prec)
+	t.Mul(t, big.NewFloat(4).SetPrec(prec))
+	return pi.Sub(pi, t)
+}
+
+// atanInverseBig computes atan(1/x) = 1/x - 1/(3x^3) + 1/(5x^5) - ...
+func atanInverseBig(x int64, prec uint) *big.Float {
+	sum := new(big.Float).SetPrec(prec)
+	term := new(big.Float).SetPrec(prec).Quo(big.NewFloat(1), new(big.Float).SetInt64(x))
+	x2 := new(big.Float).SetPrec(prec).SetInt64(x * x)
+	epsilon := new(big.Float).SetMantExp(big.NewFloat(1), -int(prec))
+	for k := int64(0); ; k++ {
+		t := new(big.Float).SetPrec(prec).Quo(term, new(big.Float).SetInt64(2*k+1))
+		if k%2 == 0 {
+			sum.Add(sum, t)
+		} else {
+			sum.Sub(sum, t)
+		}
+		if t.Cmp(epsilon) < 0 {
+			return sum
+		}
+		term.Quo(term, x2)
+	}
+}
+
+// sinCosBig evaluates the Taylor series of sin and cos for 0 <= theta <= pi/2.
+func sinCosBig(theta *big.Float, prec uint) (sin, cos *big.Float) {
+	sin = new(big.Float).SetPrec(prec)
+	cos = new(big.Float).SetPrec(prec)
+	epsilon := new(big.Float).SetMantExp(big.NewFloat(1), -int(prec))
+
+	// term is theta^k / k!, alternating between the cos (even k) and sin (odd k) series
+	term := new(big.Float).SetPrec(prec).SetInt64(1)
+	for k := int64(0); ; k++ {
+		target := cos
+		if k%2 == 1 {
+			target = sin
+		}
+		if (k/2)%2 == 0 {
+			target.Add(target, term)
+		} else {
+			target.Sub(target, term)
+		}
+		if k > 1 && term.Cmp(epsilon) < 0 {
+			return sin, cos
+		}
+		term.Mul(term, theta)
+		term.Quo(term, new(big.Float).SetInt64(k+1))
+	}
+}
+
+// rootBig is the arbitrary precision counterpart of realRoot and principalRoot.
+func rootBig(x float64, n int, digits int, complexMode bool) (re, im *big.Float) {
+	prec := digitsToBits(digits)
+	a := new(big.Float).SetPrec(prec).SetFloat64(math.Abs(x))
+	magnitude := nthRootBig(a, n, prec)
+
+	if x >= 0 {
+		return magnitude, new(big.Float)
+	}
+	if !complexMode || n == 1 {
+		// Odd degree, the real root of a negative number
+		return magnitude.Neg(magnitude), new(big.Float)
+	}
+	if n == 2 {
+		return new(big.Float), magnitude
+	}
+	angle := piBig(prec)
+	angle.Quo(angle, new(big.Float).SetInt64(int64(n)))
+	sin, cos := sinCosBig(angle, prec)
+	re = new(big.Float).SetPrec(prec).Mul(magnitude, cos)
+	im = new(big.Float).SetPrec(prec).Mul(magnitude, sin)
+	return re, im
+}
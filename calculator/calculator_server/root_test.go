@@ -0,0 +1,99 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestRealRoot(t *testing.T) {
+	tests := []struct {
+		x    float64
+		n    int
+		want float64
+	}{
+		{16, 2, 4},
+		{-27, 3, -3},
+		{32, 5, 2},
+		{-32, 5, -2},
+		{0, 7, 0},
+		{1e-300, 100, 1e-3},
+	}
+	for _, tt := range tests {
+		if got := realRoot(tt.x, tt.n); math.Abs(got-tt.want) > 1e-12*math.Max(1, math.Abs(tt.want)) {
+			t.Errorf("realRoot(%v, %d) = %v, want %v", tt.x, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestPrincipalRoot(t *testing.T) {
+	tests := []struct {
+		x      float64
+		n      int
+		re, im float64
+	}{
+		{-4, 2, 0, 2},
+		{-8, 3, 1, math.Sqrt(3)},
+		{-16, 4, math.Sqrt2, math.Sqrt2},
+	}
+	for _, tt := range tests {
+		re, im := principalRoot(tt.x, tt.n)
+		if math.Abs(re-tt.re) > 1e-12 || math.Abs(im-tt.im) > 1e-12 {
+			t.Errorf("principalRoot(%v, %d) = %v%+vi, want %v%+vi", tt.x, tt.n, re, im, tt.re, tt.im)
+		}
+	}
+}
+
+func TestRootBig(t *testing.T) {
+	// sqrt(2) to 50 significant digits
+	re, im := rootBig(2, 2, 50, false)
+	if got, want := re.Text('g', 50), "1.4142135623730950488016887242096980785696718753769"; got != want {
+		t.Errorf("rootBig(2, 2) = %v, want %v", got, want)
+	}
+	if im.Sign() != 0 {
+		t.Errorf("rootBig(2, 2) has imaginary part %v", im)
+	}
+
+	// Cube root of 2 to 40 digits
+	re, _ = rootBig(2, 3, 40, false)
+	if got, want := re.Text('g', 40), "1.25992104989487316476721060727822835057"; got != want {
+		t.Errorf("rootBig(2, 3) = %v, want %v", got, want)
+	}
+
+	// The principal cube root of -8 is 1 + sqrt(3)i
+	re, im = rootBig(-8, 3, 30, true)
+	if got := re.Text('g', 30); got != "1" {
+		t.Errorf("real part of rootBig(-8, 3) = %v, want 1", got)
+	}
+	if got, want := im.Text('g', 30), "1.73205080756887729352744634151"; got != want {
+		t.Errorf("imaginary part of rootBig(-8, 3) = %v, want %v", got, want)
+	}
+
+	// Real mode and a degree of 1 both stay on the real line
+	for _, n := range []int{1, 3} {
+		re, im = rootBig(-8, n, 20, n == 1)
+		if re.Sign() >= 0 || im.Sign() != 0 {
+			t.Errorf("rootBig(-8, %d) = %v%+vi, want a negative real", n, re, im)
+		}
+	}
+}
+
+func TestNthRootBigRoundTrips(t *testing.T) {
+	prec := digitsToBits(100)
+	a, _ := new(big.Float).SetPrec(prec).SetString("12345.6789")
+	for _, n := range []int{2, 3, 7, 50} {
+		root := nthRootBig(a, n, prec)
+		back := powBig(root, n, prec)
+		diff := new(big.Float).Sub(back, a)
+		diff.Quo(diff, a)
+		if f, _ := diff.Float64(); math.Abs(f) > 1e-95 {
+			t.Errorf("nthRootBig(%v, %d)^%d is off by a relative %v", a, n, n, f)
+		}
+	}
+	if root := nthRootBig(new(big.Float), 3, prec); root.Sign() != 0 {
+		t.Errorf("nthRootBig(0, 3) = %v, want 0", root)
+	}
+	if got, want := piBig(prec).Text('g', 30), "3.14159265358979323846264338328"; got != want {
+		t.Errorf("piBig() = %v, want %v", got, want)
+	}
+}
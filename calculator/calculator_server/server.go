@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/wiliamhw/golang-grpc-example/calculator/calculatorpb"
 	"google.golang.org/grpc"
@@ -11,20 +13,47 @@ import (
 	"io"
 	"log"
 	"math"
+	"math/big"
 	"net"
+	"strings"
+	"time"
 )
 
 const port = 50051
 
+var sessionIdleTimeout = flag.Duration("session-idle-timeout", 5*time.Minute, "How long a calculator session may stay idle")
+
 type server struct {
 	calculatorpb.UnimplementedCalculatorServiceServer
+	sessions *sessionManager
 }
 
 func (*server) Sum(ctx context.Context, req *calculatorpb.SumRequest) (*calculatorpb.SumResponse, error) {
 	fmt.Printf("Received Sum RPC: %v\n", req)
 	firstNumber := req.GetFirstNumber()
 	secondNumber := req.GetSecondNumber()
-	sum := firstNumber + secondNumber
+
+	if req.GetMode() == calculatorpb.ArithmeticMode_DECIMAL {
+		first, err := decimalOperand(req.GetFirstDecimal(), firstNumber)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid first number: %v", err)
+		}
+		second, err := decimalOperand(req.GetSecondDecimal(), secondNumber)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid second number: %v", err)
+		}
+		return &calculatorpb.SumResponse{
+			DecimalResult: formatDecimal(first.Add(first, second)),
+		}, nil
+	}
+
+	sum, ok := checkedAdd(firstNumber, secondNumber)
+	if !ok {
+		return nil, status.Errorf(
+			codes.OutOfRange,
+			fmt.Sprintf("Sum of %v and %v overflows int64", firstNumber, secondNumber),
+		)
+	}
 	res := &calculatorpb.SumResponse{
 		SumResult: sum,
 	}
@@ -36,24 +65,117 @@ func (*server) PrimeNumberDecomposition(
 	stream calculatorpb.CalculatorService_PrimeNumberDecompositionServer,
 ) error {
 	fmt.Printf("Received PrimeNumberDecomposition RPC: %v\n", req)
-	number := req.GetNumber()
-	divisor := int64(2)
+	number, err := parseBigNumber(req.GetNumber(), req.GetDecimalNumber(), maxFactorDigits)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid number: %v", err)
+	}
+	if number.Sign() <= 0 {
+		return status.Errorf(
+			codes.InvalidArgument,
+			fmt.Sprintf("Received a non-positive number: %v", number),
+		)
+	}
 
-	for number > 1 {
-		if number%divisor != 0 {
-			divisor++
-			fmt.Printf("Divisor has increased to %v\n", divisor)
-			continue
-		}
+	ctx, cancel := context.WithTimeout(stream.Context(), maxFactorTime)
+	defer cancel()
+	unfactored, err := factorize(ctx, number, func(prime *big.Int, multiplicity int) error {
 		res := &calculatorpb.PrimeNumberDecompositionResponse{
-			PrimeNumber: divisor,
+			DecimalPrime: prime.String(),
+			Multiplicity: int32(multiplicity),
 		}
-		err := stream.Send(res)
-		if err != nil {
+		if prime.IsInt64() {
+			res.PrimeNumber = prime.Int64()
+		}
+		return stream.Send(res)
+	})
+	if err == context.DeadlineExceeded && stream.Context().Err() == nil {
+		// Out of time, the client still gets the partial factorization
+		if err := stream.Send(&calculatorpb.PrimeNumberDecompositionResponse{Unfactored: unfactored.String()}); err != nil {
 			log.Printf("Erorr while sending data to client: %v\n", err)
 			return err
 		}
-		number /= divisor
+		return status.Errorf(
+			codes.DeadlineExceeded,
+			"Could not factor %v within %v", unfactored, maxFactorTime,
+		)
+	}
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return status.FromContextError(err).Err()
+	}
+	if err != nil {
+		log.Printf("Erorr while sending data to client: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+func (*server) IsPrime(ctx context.Context, req *calculatorpb.IsPrimeRequest) (*calculatorpb.IsPrimeResponse, error) {
+	fmt.Printf("Received IsPrime RPC: %v\n", req)
+	number, err := parseBigNumber(req.GetNumber(), req.GetDecimalNumber(), maxPrimeDigits)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid number: %v", err)
+	}
+
+	prime, deterministic := isPrime(number)
+	res := &calculatorpb.IsPrimeResponse{
+		IsPrime:       prime,
+		Deterministic: deterministic,
+		Certainty:     1,
+	}
+	if prime && !deterministic {
+		res.Certainty = primeCertainty
+	}
+	return res, nil
+}
+
+func (*server) NextPrime(ctx context.Context, req *calculatorpb.NextPrimeRequest) (*calculatorpb.NextPrimeResponse, error) {
+	fmt.Printf("Received NextPrime RPC: %v\n", req)
+	number, err := parseBigNumber(req.GetNumber(), req.GetDecimalNumber(), maxPrimeDigits)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid number: %v", err)
+	}
+
+	prime, err := nextPrime(ctx, number)
+	if err != nil {
+		return nil, status.FromContextError(err).Err()
+	}
+	res := &calculatorpb.NextPrimeResponse{
+		DecimalPrime:  prime.String(),
+		Deterministic: prime.BitLen() <= 64,
+		Certainty:     1,
+	}
+	if prime.IsInt64() {
+		res.Prime = prime.Int64()
+	}
+	if !res.Deterministic {
+		res.Certainty = primeCertainty
+	}
+	return res, nil
+}
+
+func (*server) GeneratePrimes(
+	req *calculatorpb.GeneratePrimesRequest,
+	stream calculatorpb.CalculatorService_GeneratePrimesServer,
+) error {
+	fmt.Printf("Received GeneratePrimes RPC: %v\n", req)
+	if req.GetTo() > maxGeneratePrimesTo {
+		return status.Errorf(
+			codes.InvalidArgument,
+			fmt.Sprintf("Cannot generate primes above %d", int64(maxGeneratePrimesTo)),
+		)
+	}
+
+	// Send blocks while the client's flow control window is full, so a slow
+	// client holds back the sieve instead of buffering primes on the server.
+	err := generatePrimes(stream.Context(), req.GetFrom(), req.GetTo(), func(primes []int64) error {
+		return stream.Send(&calculatorpb.GeneratePrimesResponse{Primes: primes})
+	})
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return status.FromContextError(err).Err()
+	}
+	if err != nil {
+		log.Printf("Erorr while sending data to client: %v\n", err)
+		return err
 	}
 	return nil
 }
@@ -61,8 +183,9 @@ func (*server) PrimeNumberDecomposition(
 func (*server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAverageServer) error {
 	fmt.Println("ComputeAverage function was invoked with a streaming request")
 
-	var sum int32 = 0
-	count := 0
+	// Summed exactly so long streams can neither overflow nor lose precision
+	sum := new(big.Rat)
+	count := int64(0)
 
 	for {
 		req, err := stream.Recv()
@@ -73,14 +196,88 @@ func (*server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAvera
 			log.Printf("Erorr while reading client stream: %v", err)
 			return err
 		}
-		number := req.GetNumber()
-		sum += number
+		number := new(big.Rat).SetInt64(req.GetNumber())
+		if req.GetDecimalNumber() != "" {
+			number, err = parseDecimal(req.GetDecimalNumber())
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "Invalid number: %v", err)
+			}
+		}
+		sum.Add(sum, number)
 		count++
 	}
-	average := float64(sum) / float64(count)
-	err := stream.SendAndClose(&calculatorpb.ComputeAverageResponse{
-		Average: average,
-	})
+	if count == 0 {
+		return status.Errorf(codes.InvalidArgument, "Cannot average an empty stream")
+	}
+	average := sum.Quo(sum, new(big.Rat).SetInt64(count))
+	res := &calculatorpb.ComputeAverageResponse{
+		DecimalAverage: formatDecimal(average),
+	}
+	res.Average, _ = average.Float64()
+	err := stream.SendAndClose(res)
+	if err != nil {
+		log.Printf("Erorr while sending data to client: %v\n", err)
+	}
+	return err
+}
+
+func (*server) ComputeStatistics(stream calculatorpb.CalculatorService_ComputeStatisticsServer) error {
+	fmt.Println("ComputeStatistics function was invoked with a streaming request")
+
+	stats := &runningStats{}
+	sketch := newQuantileSketch(quantileSketchK)
+	var percentiles []float64
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break // Finish reading the client stream
+		}
+		if err != nil {
+			log.Printf("Erorr while reading client stream: %v", err)
+			return err
+		}
+		number := req.GetNumber()
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return status.Errorf(codes.InvalidArgument, "Received a non-finite number: %v", number)
+		}
+		for _, p := range req.GetPercentiles() {
+			if !(p >= 0 && p <= 100) {
+				return status.Errorf(codes.InvalidArgument, "Percentile must be between 0 and 100: %v", p)
+			}
+			if len(percentiles) >= maxPercentiles {
+				return status.Errorf(codes.InvalidArgument, "Cannot compute more than %d percentiles", maxPercentiles)
+			}
+			percentiles = append(percentiles, p)
+		}
+		stats.add(number)
+		sketch.add(number)
+	}
+
+	if stats.count == 0 {
+		return status.Errorf(codes.InvalidArgument, "Cannot compute statistics of an empty stream")
+	}
+	if len(percentiles) == 0 {
+		percentiles = []float64{50, 90, 95, 99}
+	}
+
+	res := &calculatorpb.ComputeStatisticsResponse{
+		Count:              stats.count,
+		Min:                stats.min,
+		Max:                stats.max,
+		Mean:               stats.mean,
+		PopulationVariance: stats.populationVariance(),
+		SampleVariance:     stats.sampleVariance(),
+		StandardDeviation:  math.Sqrt(stats.sampleVariance()),
+		Median:             sketch.quantile(0.5),
+	}
+	for _, p := range percentiles {
+		res.Percentiles = append(res.Percentiles, &calculatorpb.Percentile{
+			Percentile: p,
+			Value:      sketch.quantile(p / 100),
+		})
+	}
+	err := stream.SendAndClose(res)
 	if err != nil {
 		log.Printf("Erorr while sending data to client: %v\n", err)
 	}
@@ -89,7 +286,8 @@ func (*server) ComputeAverage(stream calculatorpb.CalculatorService_ComputeAvera
 
 func (*server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServer) error {
 	fmt.Println("FindMaximum function was invoked with a streaming request")
-	var maximum int32 = 0
+	var maximum int64
+	seen := false
 
 	for {
 		req, err := stream.Recv()
@@ -102,10 +300,11 @@ func (*server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServ
 		}
 		number := req.GetNumber()
 
-		if number <= maximum {
+		if seen && number <= maximum {
 			continue
 		}
 		maximum = number
+		seen = true
 		sendErr := stream.Send(&calculatorpb.FindMaximumResponse{
 			Maximum: maximum,
 		})
@@ -116,6 +315,117 @@ func (*server) FindMaximum(stream calculatorpb.CalculatorService_FindMaximumServ
 	}
 }
 
+func (*server) Aggregate(stream calculatorpb.CalculatorService_AggregateServer) error {
+	fmt.Println("Aggregate function was invoked with a streaming request")
+
+	// Receive in the background so values leaving a time window are reported without new input
+	requests := make(chan *calculatorpb.AggregateRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	var agg aggregator
+	var last []int64
+	expiry := time.NewTimer(time.Hour)
+	expiry.Stop()
+	defer expiry.Stop()
+
+	for {
+		select {
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			log.Printf("Erorr while reading client stream: %v\n", err)
+			return err
+		case req := <-requests:
+			if agg == nil {
+				var err error
+				if agg, err = newAggregator(req.GetConfig()); err != nil {
+					return status.Errorf(codes.InvalidArgument, "Invalid aggregation: %v", err)
+				}
+			}
+			if err := agg.add(req.GetNumber(), time.Now()); err != nil {
+				return status.Errorf(codes.ResourceExhausted, "Cannot aggregate: %v, use a smaller window", err)
+			}
+		case now := <-expiry.C:
+			agg.expire(now)
+		}
+
+		if when, ok := agg.nextExpiry(); ok {
+			if !expiry.Stop() {
+				select {
+				case <-expiry.C:
+				default:
+				}
+			}
+			expiry.Reset(time.Until(when))
+		}
+
+		result := agg.result()
+		if equalResults(result, last) {
+			continue
+		}
+		last = result
+		sendErr := stream.Send(&calculatorpb.AggregateResponse{
+			Values: result,
+		})
+		if sendErr != nil {
+			log.Printf("Erorr while sending data to client: %v\n", sendErr)
+			return sendErr
+		}
+	}
+}
+
+// newAggregator validates the configuration sent on the first Aggregate message.
+func newAggregator(config *calculatorpb.AggregationConfig) (aggregator, error) {
+	switch config.GetMode() {
+	case calculatorpb.AggregationMode_RUNNING_MAX:
+		return &runningExtreme{better: greater}, nil
+	case calculatorpb.AggregationMode_RUNNING_MIN:
+		return &runningExtreme{better: less}, nil
+	case calculatorpb.AggregationMode_WINDOW_MAX, calculatorpb.AggregationMode_WINDOW_MIN:
+		size, millis := config.GetWindowSize(), config.GetWindowMillis()
+		if size < 0 || millis < 0 || (size == 0 && millis == 0) {
+			return nil, fmt.Errorf("a window needs a positive window_size or window_millis")
+		}
+		if size > maxWindowValues {
+			return nil, fmt.Errorf("window_size cannot be larger than %d", maxWindowValues)
+		}
+		// Checked before converting, large values overflow a time.Duration
+		if millis > maxWindowSpan.Milliseconds() {
+			return nil, fmt.Errorf("window_millis cannot be larger than %d", maxWindowSpan.Milliseconds())
+		}
+		better := greater
+		if config.GetMode() == calculatorpb.AggregationMode_WINDOW_MIN {
+			better = less
+		}
+		return &windowExtreme{
+			better: better,
+			size:   int(size),
+			span:   time.Duration(millis) * time.Millisecond,
+		}, nil
+	case calculatorpb.AggregationMode_TOP_K:
+		if config.GetK() < 1 || config.GetK() > maxTopK {
+			return nil, fmt.Errorf("k must be between 1 and %d", maxTopK)
+		}
+		return &topK{k: int(config.GetK())}, nil
+	}
+	return nil, fmt.Errorf("unknown mode %v", config.GetMode())
+}
+
 func (*server) SquareRoot(
 	ctx context.Context,
 	req *calculatorpb.SquareRootRequest,
@@ -134,8 +444,368 @@ func (*server) SquareRoot(
 	}, nil
 }
 
+func (*server) Root(ctx context.Context, req *calculatorpb.RootRequest) (*calculatorpb.RootResponse, error) {
+	fmt.Printf("Received Root RPC: %v\n", req)
+	radicand := req.GetRadicand()
+	degree := int(req.GetDegree())
+	if degree == 0 {
+		degree = 2
+	}
+	precision := int(req.GetPrecision())
+	complexMode := req.GetMode() == calculatorpb.RootMode_COMPLEX
+
+	if math.IsNaN(radicand) || math.IsInf(radicand, 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "Received a non-finite number: %v", radicand)
+	}
+	if degree < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Received a negative degree: %v", degree)
+	}
+	if precision < 0 || precision > maxRootPrecision {
+		return nil, status.Errorf(codes.InvalidArgument, "Precision must be between 0 and %d", maxRootPrecision)
+	}
+	if radicand < 0 && degree%2 == 0 && !complexMode {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			fmt.Sprintf("Received a negative number: %v", radicand),
+		)
+	}
+
+	res := &calculatorpb.RootResponse{}
+	if radicand < 0 && complexMode && degree > 1 {
+		res.Real, res.Imaginary = principalRoot(radicand, degree)
+	} else {
+		res.Real = realRoot(radicand, degree)
+	}
+	if precision > 0 {
+		re, im := rootBig(radicand, degree, precision, complexMode)
+		res.DecimalReal = re.Text('g', precision)
+		res.DecimalImaginary = im.Text('g', precision)
+	}
+	return res, nil
+}
+
+func (*server) AddMatrices(ctx context.Context, req *calculatorpb.AddMatricesRequest) (*calculatorpb.AddMatricesResponse, error) {
+	fmt.Println("Received AddMatrices RPC")
+	a, b, err := matrixPair(req.GetA(), req.GetB())
+	if err != nil {
+		return nil, matrixStatus("add matrices", err)
+	}
+	result, err := addMatrices(a, b)
+	if err != nil {
+		return nil, matrixStatus("add matrices", err)
+	}
+	return &calculatorpb.AddMatricesResponse{Result: result.toProto()}, nil
+}
+
+func (*server) MultiplyMatrices(
+	ctx context.Context,
+	req *calculatorpb.MultiplyMatricesRequest,
+) (*calculatorpb.MultiplyMatricesResponse, error) {
+	fmt.Println("Received MultiplyMatrices RPC")
+	a, b, err := matrixPair(req.GetA(), req.GetB())
+	if err != nil {
+		return nil, matrixStatus("multiply matrices", err)
+	}
+	result, err := multiplyMatrices(a, b)
+	if err != nil {
+		return nil, matrixStatus("multiply matrices", err)
+	}
+	return &calculatorpb.MultiplyMatricesResponse{Result: result.toProto()}, nil
+}
+
+func (*server) Transpose(ctx context.Context, req *calculatorpb.TransposeRequest) (*calculatorpb.TransposeResponse, error) {
+	fmt.Println("Received Transpose RPC")
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, matrixStatus("transpose", err)
+	}
+	return &calculatorpb.TransposeResponse{Result: transpose(m).toProto()}, nil
+}
+
+func (*server) Determinant(ctx context.Context, req *calculatorpb.DeterminantRequest) (*calculatorpb.DeterminantResponse, error) {
+	fmt.Println("Received Determinant RPC")
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, matrixStatus("compute the determinant", err)
+	}
+	det, err := determinant(m)
+	if err != nil {
+		return nil, matrixStatus("compute the determinant", err)
+	}
+	return &calculatorpb.DeterminantResponse{Determinant: det}, nil
+}
+
+func (*server) Inverse(ctx context.Context, req *calculatorpb.InverseRequest) (*calculatorpb.InverseResponse, error) {
+	fmt.Println("Received Inverse RPC")
+	m, err := matrixFromProto("matrix", req.GetMatrix())
+	if err != nil {
+		return nil, matrixStatus("invert", err)
+	}
+	result, err := inverse(m)
+	if err != nil {
+		return nil, matrixStatus("invert", err)
+	}
+	return &calculatorpb.InverseResponse{Result: result.toProto()}, nil
+}
+
+func (*server) SolveLinearSystem(
+	ctx context.Context,
+	req *calculatorpb.SolveLinearSystemRequest,
+) (*calculatorpb.SolveLinearSystemResponse, error) {
+	fmt.Println("Received SolveLinearSystem RPC")
+	a, err := matrixFromProto("a", req.GetA())
+	if err != nil {
+		return nil, matrixStatus("solve the linear system", err)
+	}
+	b, err := vectorFromProto("b", req.GetB())
+	if err != nil {
+		return nil, matrixStatus("solve the linear system", err)
+	}
+	x, err := solveLinearSystem(a, b)
+	if err != nil {
+		return nil, matrixStatus("solve the linear system", err)
+	}
+	return &calculatorpb.SolveLinearSystemResponse{X: &calculatorpb.Vector{Values: x}}, nil
+}
+
+func matrixPair(a, b *calculatorpb.Matrix) (*matrix, *matrix, error) {
+	ma, err := matrixFromProto("a", a)
+	if err != nil {
+		return nil, nil, err
+	}
+	mb, err := matrixFromProto("b", b)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ma, mb, nil
+}
+
+// matrixStatus maps singular matrices to FAILED_PRECONDITION and every other
+// error to INVALID_ARGUMENT, operation naming what could not be done.
+func matrixStatus(operation string, err error) error {
+	if errors.Is(err, errSingularMatrix) {
+		return status.Errorf(codes.FailedPrecondition, "Cannot %s: %v", operation, err)
+	}
+	return status.Errorf(codes.InvalidArgument, "Cannot %s, invalid matrix: %v", operation, err)
+}
+
+func (*server) Convert(ctx context.Context, req *calculatorpb.ConvertRequest) (*calculatorpb.ConvertResponse, error) {
+	fmt.Printf("Received Convert RPC: %v\n", req)
+	value, dimension, err := convert(req.GetValue(), req.GetFromUnit(), req.GetToUnit())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Cannot convert: %v", err)
+	}
+	return &calculatorpb.ConvertResponse{Value: value, Dimension: dimension}, nil
+}
+
+func (*server) ListUnits(ctx context.Context, req *calculatorpb.ListUnitsRequest) (*calculatorpb.ListUnitsResponse, error) {
+	fmt.Printf("Received ListUnits RPC: %v\n", req)
+	list, err := unitsOf(req.GetDimension())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Cannot list units: %v", err)
+	}
+	res := &calculatorpb.ListUnitsResponse{}
+	for _, u := range list {
+		pb := &calculatorpb.Unit{Symbol: u.symbol, Name: u.name, Dimension: u.dimension}
+		for _, p := range u.prefixes {
+			pb.Prefixes = append(pb.Prefixes, p.symbol)
+		}
+		res.Units = append(res.Units, pb)
+	}
+	return res, nil
+}
+
+func (*server) Evaluate(
+	ctx context.Context,
+	req *calculatorpb.EvaluateRequest,
+) (*calculatorpb.EvaluateResponse, error) {
+	fmt.Printf("Received Evaluate RPC: %v\n", req)
+
+	node, err := parseExpression(req.GetExpression())
+	if err == nil && req.GetMode() == calculatorpb.ArithmeticMode_DECIMAL {
+		var result *big.Rat
+		result, err = evaluateDecimal(node)
+		if err == nil {
+			return &calculatorpb.EvaluateResponse{
+				Outcome: &calculatorpb.EvaluateResponse_DecimalResult{DecimalResult: formatDecimal(result)},
+			}, nil
+		}
+	} else if err == nil {
+		var result float64
+		result, err = evaluate(node, nil)
+		if err == nil {
+			return &calculatorpb.EvaluateResponse{
+				Outcome: &calculatorpb.EvaluateResponse_Result{Result: result},
+			}, nil
+		}
+	}
+	return evaluationErrorResponse(err)
+}
+
+func (s *server) CalculatorSession(stream calculatorpb.CalculatorService_CalculatorSessionServer) error {
+	fmt.Println("CalculatorSession function was invoked with a streaming request")
+
+	// Cancelled when another stream resumes the same session
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	// Receive in the background so the idle timer can interrupt a silent client
+	requests := make(chan *calculatorpb.CalculatorSessionRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var sess *session
+	var owner uint64
+	defer func() {
+		if sess != nil {
+			s.sessions.detach(sess, owner)
+		}
+	}()
+
+	idle := time.NewTimer(s.sessions.idleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if stream.Context().Err() == nil {
+				return status.Error(codes.Aborted, "Session was resumed by another stream")
+			}
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-idle.C:
+			return status.Errorf(codes.DeadlineExceeded, "Session was idle for %v", s.sessions.idleTimeout)
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			log.Printf("Erorr while reading client stream: %v\n", err)
+			return err
+		case req := <-requests:
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(s.sessions.idleTimeout)
+
+			if sess == nil {
+				var err error
+				sess, owner, err = s.sessions.attach(req.GetSessionId(), cancel)
+				switch err {
+				case nil:
+				case errSessionNotFound:
+					return status.Errorf(codes.NotFound, "Cannot resume session %v: %v", req.GetSessionId(), err)
+				case errTooManySessions:
+					return status.Errorf(codes.ResourceExhausted, "Cannot open session: %v", err)
+				default:
+					return status.Errorf(codes.Internal, "Cannot open session: %v", err)
+				}
+			} else if req.GetSessionId() != "" && req.GetSessionId() != sess.id {
+				return status.Errorf(codes.InvalidArgument, "Stream is already attached to session %v", sess.id)
+			}
+
+			res, err := s.runStatement(sess, owner, req.GetStatement())
+			if err != nil {
+				return err
+			}
+			if sendErr := stream.Send(res); sendErr != nil {
+				log.Printf("Erorr while sending data to client: %v\n", sendErr)
+				return sendErr
+			}
+		}
+	}
+}
+
+// runStatement evaluates a statement in the session. Statement errors are
+// reported in the response, the returned error ends the stream.
+func (s *server) runStatement(
+	sess *session,
+	owner uint64,
+	statement string,
+) (*calculatorpb.CalculatorSessionResponse, error) {
+	res := &calculatorpb.CalculatorSessionResponse{
+		SessionId: sess.id,
+		Statement: statement,
+	}
+	if err := s.sessions.touch(sess, owner); err != nil {
+		return nil, status.Errorf(codes.Aborted, "Cannot run statement: %v", err)
+	}
+	// An empty statement only reports the session id
+	if strings.TrimSpace(statement) == "" {
+		return res, nil
+	}
+
+	name, node, err := parseStatement(statement)
+	var result float64
+	if err == nil {
+		result, err = evaluate(node, func(name string) (float64, bool) {
+			return s.sessions.lookup(sess, name)
+		})
+	}
+	if err == nil && name != "" {
+		err = s.sessions.assign(sess, owner, name, result)
+	}
+
+	switch e := err.(type) {
+	case nil:
+		res.Outcome = &calculatorpb.CalculatorSessionResponse_Result{Result: result}
+	case *exprError:
+		res.Outcome = &calculatorpb.CalculatorSessionResponse_Error{Error: &calculatorpb.EvaluationError{
+			Message:  e.Msg,
+			Position: int32(e.Pos),
+		}}
+	case *limitError:
+		res.Outcome = &calculatorpb.CalculatorSessionResponse_Error{Error: &calculatorpb.EvaluationError{
+			Message: e.Msg,
+		}}
+	default:
+		if err == errTooManyVariables {
+			res.Outcome = &calculatorpb.CalculatorSessionResponse_Error{Error: &calculatorpb.EvaluationError{
+				Message: err.Error(),
+			}}
+			break
+		}
+		return nil, status.Errorf(codes.Aborted, "Cannot run statement: %v", err)
+	}
+	return res, nil
+}
+
+// evaluationErrorResponse reports expression errors in the response and limit violations as INVALID_ARGUMENT.
+func evaluationErrorResponse(err error) (*calculatorpb.EvaluateResponse, error) {
+	switch e := err.(type) {
+	case *exprError:
+		return &calculatorpb.EvaluateResponse{
+			Outcome: &calculatorpb.EvaluateResponse_Error{Error: &calculatorpb.EvaluationError{
+				Message:  e.Msg,
+				Position: int32(e.Pos),
+			}},
+		}, nil
+	case *limitError:
+		return nil, status.Errorf(codes.InvalidArgument, "Expression rejected: %v", e.Msg)
+	}
+	return nil, status.Errorf(codes.Internal, "Unexpected evaluation error: %v", err)
+}
+
 func main() {
 	fmt.Println("Calculator Server")
+	flag.Parse()
+	if *sessionIdleTimeout < minSessionIdleTimeout {
+		log.Fatalf("Invalid -session-idle-timeout %v, it must be at least %v", *sessionIdleTimeout, minSessionIdleTimeout)
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
@@ -143,7 +813,9 @@ func main() {
 	}
 
 	s := grpc.NewServer()
-	calculatorpb.RegisterCalculatorServiceServer(s, &server{})
+	calculatorpb.RegisterCalculatorServiceServer(s, &server{
+		sessions: newSessionManager(*sessionIdleTimeout),
+	})
 
 	// Register reflection service on gRPC server.
 	reflection.Register(s)
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Limits protecting the server from clients that never release their sessions.
+const (
+	maxSessions         = 1000
+	maxSessionVariables = 256
+)
+
+// minSessionIdleTimeout keeps the expiry loop, which ticks every half timeout, from ticking every 0s.
+const minSessionIdleTimeout = time.Second
+
+var (
+	errSessionNotFound  = errors.New("session not found or expired")
+	errTooManySessions  = errors.New("too many open sessions")
+	errTooManyVariables = errors.New("too many variables in session")
+	errSessionTakenOver = errors.New("session was resumed by another stream")
+)
+
+// session is the variable environment of a CalculatorSession. It outlives the
+// stream that created it so a client can resume it after reconnecting.
+type session struct {
+	id         string
+	vars       map[string]float64
+	lastActive time.Time
+
+	// owner identifies the stream currently attached, cancel ends that stream.
+	owner  uint64
+	cancel func()
+}
+
+type sessionManager struct {
+	mu          sync.Mutex
+	sessions    map[string]*session
+	nextOwner   uint64
+	idleTimeout time.Duration
+}
+
+func newSessionManager(idleTimeout time.Duration) *sessionManager {
+	m := &sessionManager{
+		sessions:    map[string]*session{},
+		idleTimeout: idleTimeout,
+	}
+	go m.expireLoop()
+	return m
+}
+
+// attach resumes the session with the given id, or creates a new one when id
+// is empty. A stream already attached to the session is cancelled.
+func (m *sessionManager) attach(id string, cancel func()) (*session, uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var sess *session
+	if id == "" {
+		if len(m.sessions) >= maxSessions {
+			return nil, 0, errTooManySessions
+		}
+		sess = &session{id: newSessionID(), vars: map[string]float64{}}
+		m.sessions[sess.id] = sess
+	} else {
+		var ok bool
+		if sess, ok = m.sessions[id]; !ok {
+			return nil, 0, errSessionNotFound
+		}
+		if sess.cancel != nil {
+			sess.cancel()
+		}
+	}
+
+	m.nextOwner++
+	sess.owner = m.nextOwner
+	sess.cancel = cancel
+	sess.lastActive = time.Now()
+	return sess, sess.owner, nil
+}
+
+// detach releases the session. It stays resumable until it has been idle for the timeout.
+func (m *sessionManager) detach(sess *session, owner uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sess.owner == owner {
+		sess.cancel = nil
+		sess.lastActive = time.Now()
+	}
+}
+
+// lookup returns the value of a session variable for the attached stream.
+func (m *sessionManager) lookup(sess *session, name string) (float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v, ok := sess.vars[name]
+	return v, ok
+}
+
+// touch marks the session as active, failing if another stream took it over.
+func (m *sessionManager) touch(sess *session, owner uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sess.owner != owner {
+		return errSessionTakenOver
+	}
+	sess.lastActive = time.Now()
+	return nil
+}
+
+func (m *sessionManager) assign(sess *session, owner uint64, name string, value float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sess.owner != owner {
+		return errSessionTakenOver
+	}
+	if _, ok := sess.vars[name]; !ok && len(sess.vars) >= maxSessionVariables {
+		return errTooManyVariables
+	}
+	sess.vars[name] = value
+	return nil
+}
+
+// expireLoop drops detached sessions once they have been idle for the timeout.
+func (m *sessionManager) expireLoop() {
+	ticker := time.NewTicker(m.idleTimeout / 2)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.mu.Lock()
+		for id, sess := range m.sessions {
+			if sess.cancel == nil && now.Sub(sess.lastActive) > m.idleTimeout {
+				delete(m.sessions, id)
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
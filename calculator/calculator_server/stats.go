@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	quantileSketchK = 200
+	maxPercentiles  = 100
+)
+
+// runningStats accumulates count, min, max, mean and variance in one pass
+// using Welford's algorithm, which does not lose precision on long streams
+// the way summing squares does.
+type runningStats struct {
+	count    int64
+	min, max float64
+	mean     float64
+	m2       float64 // sum of squared distances from the mean
+}
+
+func (s *runningStats) add(x float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max = x, x
+	} else {
+		s.min = math.Min(s.min, x)
+		s.max = math.Max(s.max, x)
+	}
+	delta := x - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (x - s.mean)
+}
+
+func (s *runningStats) populationVariance() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.count)
+}
+
+func (s *runningStats) sampleVariance() float64 {
+	if s.count < 2 {
+		return 0
+	}
+	return s.m2 / float64(s.count-1)
+}
+
+// quantileSketch is a KLL sketch answering approximate quantile queries in
+// memory proportional to k, however many values are added. With k = 200 the
+// rank error is typically below 1.5%.
+type quantileSketch struct {
+	k          int
+	compactors [][]float64 // level h holds values that each stand for 2^h inputs
+	size       int
+}
+
+func newQuantileSketch(k int) *quantileSketch {
+	return &quantileSketch{k: k, compactors: make([][]float64, 1)}
+}
+
+func (s *quantileSketch) add(x float64) {
+	s.compactors[0] = append(s.compactors[0], x)
+	s.size++
+	if s.size >= s.maxSize() {
+		s.compress()
+	}
+}
+
+// capacity shrinks geometrically for the lower levels.
+func (s *quantileSketch) capacity(level int) int {
+	depth := len(s.compactors) - level - 1
+	c := int(math.Ceil(float64(s.k) * math.Pow(2.0/3.0, float64(depth))))
+	if c < 2 {
+		return 2
+	}
+	return c
+}
+
+func (s *quantileSketch) maxSize() int {
+	total := 0
+	for level := range s.compactors {
+		total += s.capacity(level)
+	}
+	return total
+}
+
+// compress halves the first full level, promoting every other value.
+func (s *quantileSketch) compress() {
+	for level := range s.compactors {
+		if len(s.compactors[level]) < s.capacity(level) {
+			continue
+		}
+		if level+1 == len(s.compactors) {
+			s.compactors = append(s.compactors, nil)
+		}
+		values := s.compactors[level]
+		sort.Float64s(values)
+		for i := rand.Intn(2); i < len(values); i += 2 {
+			s.compactors[level+1] = append(s.compactors[level+1], values[i])
+		}
+		s.compactors[level] = values[:0]
+
+		s.size = 0
+		for _, c := range s.compactors {
+			s.size += len(c)
+		}
+		return
+	}
+}
+
+// quantile returns an approximation of the value at rank q*count, q in [0, 1].
+func (s *quantileSketch) quantile(q float64) float64 {
+	type weighted struct {
+		value  float64
+		weight int64
+	}
+	var items []weighted
+	var total int64
+	for level, values := range s.compactors {
+		for _, v := range values {
+			items = append(items, weighted{v, 1 << level})
+			total += 1 << level
+		}
+	}
+	if len(items) == 0 {
+		return math.NaN()
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].value < items[j].value })
+
+	target := q * float64(total)
+	var cumulative int64
+	for _, item := range items {
+		cumulative += item.weight
+		if float64(cumulative) >= target {
+			return item.value
+		}
+	}
+	return items[len(items)-1].value
+}
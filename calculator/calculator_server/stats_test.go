@@ -0,0 +1,80 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestRunningStats(t *testing.T) {
+	var s runningStats
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		s.add(x)
+	}
+	if s.count != 8 || s.min != 2 || s.max != 9 || s.mean != 5 {
+		t.Errorf("count, min, max, mean = %v, %v, %v, %v, want 8, 2, 9, 5", s.count, s.min, s.max, s.mean)
+	}
+	if v := s.populationVariance(); v != 4 {
+		t.Errorf("populationVariance() = %v, want 4", v)
+	}
+	if v := s.sampleVariance(); math.Abs(v-32.0/7) > 1e-12 {
+		t.Errorf("sampleVariance() = %v, want %v", v, 32.0/7)
+	}
+
+	var single runningStats
+	single.add(-3)
+	if single.populationVariance() != 0 || single.sampleVariance() != 0 || single.min != -3 || single.max != -3 {
+		t.Errorf("stats of a single value = %+v", single)
+	}
+}
+
+func TestRunningStatsKeepsPrecision(t *testing.T) {
+	// Summing squares would cancel out catastrophically around 1e9
+	var s runningStats
+	for i := 0; i < 1000; i++ {
+		s.add(1e9 + float64(i%2))
+	}
+	if v := s.populationVariance(); math.Abs(v-0.25) > 1e-6 {
+		t.Errorf("populationVariance() = %v, want 0.25", v)
+	}
+}
+
+func TestQuantileSketchIsExactWhenSmall(t *testing.T) {
+	s := newQuantileSketch(quantileSketchK)
+	if !math.IsNaN(s.quantile(0.5)) {
+		t.Errorf("quantile of an empty sketch = %v, want NaN", s.quantile(0.5))
+	}
+	for _, x := range []float64{5, 1, 4, 2, 3} {
+		s.add(x)
+	}
+	tests := map[float64]float64{0: 1, 0.2: 1, 0.5: 3, 0.9: 5, 1: 5}
+	for q, want := range tests {
+		if got := s.quantile(q); got != want {
+			t.Errorf("quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestQuantileSketchRankError(t *testing.T) {
+	const n = 200000
+	s := newQuantileSketch(quantileSketchK)
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		s.add(float64(i))
+	}
+
+	retained := 0
+	for _, c := range s.compactors {
+		retained += len(c)
+	}
+	if retained > 4*quantileSketchK {
+		t.Errorf("sketch retains %d values, want at most %d", retained, 4*quantileSketchK)
+	}
+
+	// The value of rank q*n is q*n itself, so the error is a rank error
+	for q := 0.01; q < 1; q += 0.01 {
+		got := s.quantile(q)
+		if rankError := math.Abs(got-q*n) / n; rankError > 0.03 {
+			t.Errorf("quantile(%.2f) = %v, rank error %.4f", q, got, rankError)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+const (
+	dimensionLength      = "length"
+	dimensionMass        = "mass"
+	dimensionTime        = "time"
+	dimensionTemperature = "temperature"
+	dimensionData        = "data"
+	dimensionSpeed       = "speed"
+)
+
+var dimensions = []string{
+	dimensionLength, dimensionMass, dimensionTime, dimensionTemperature, dimensionData, dimensionSpeed,
+}
+
+// unit converts to the base unit of its dimension with base = value*factor + offset.
+// Only temperatures have an offset.
+type unit struct {
+	symbol    string
+	name      string
+	dimension string
+	factor    *big.Rat
+	offset    *big.Rat
+	prefixes  []prefix // the prefixes the unit accepts, if any
+}
+
+type prefix struct {
+	symbol string
+	name   string
+	factor *big.Rat
+}
+
+// exact parses a factor like "0.3048", "1e-6" or "5/9" so conversions are exact until the final rounding.
+func exact(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic("invalid unit factor " + s)
+	}
+	return r
+}
+
+var siPrefixes = []prefix{
+	{"Q", "quetta", exact("1e30")}, {"R", "ronna", exact("1e27")}, {"Y", "yotta", exact("1e24")}, {"Z", "zetta", exact("1e21")},
+	{"E", "exa", exact("1e18")}, {"P", "peta", exact("1e15")}, {"T", "tera", exact("1e12")}, {"G", "giga", exact("1e9")},
+	{"M", "mega", exact("1e6")}, {"k", "kilo", exact("1e3")}, {"h", "hecto", exact("1e2")}, {"da", "deca", exact("1e1")},
+	{"d", "deci", exact("1e-1")}, {"c", "centi", exact("1e-2")}, {"m", "milli", exact("1e-3")}, {"u", "micro", exact("1e-6")},
+	{"µ", "micro", exact("1e-6")}, {"n", "nano", exact("1e-9")}, {"p", "pico", exact("1e-12")}, {"f", "femto", exact("1e-15")},
+	{"a", "atto", exact("1e-18")}, {"z", "zepto", exact("1e-21")}, {"y", "yocto", exact("1e-24")}, {"r", "ronto", exact("1e-27")},
+	{"q", "quecto", exact("1e-30")},
+}
+
+// dataPrefixes are the decimal prefixes above one plus the binary ones.
+var dataPrefixes = []prefix{
+	{"k", "kilo", exact("1e3")}, {"M", "mega", exact("1e6")}, {"G", "giga", exact("1e9")}, {"T", "tera", exact("1e12")},
+	{"P", "peta", exact("1e15")}, {"E", "exa", exact("1e18")},
+	{"Ki", "kibi", exact("1024")}, {"Mi", "mebi", exact("1048576")}, {"Gi", "gibi", exact("1073741824")}, {"Ti", "tebi", exact("1099511627776")},
+	{"Pi", "pebi", exact("1125899906842624")}, {"Ei", "exbi", exact("1152921504606846976")},
+}
+
+// units is the registry, the first unit of every dimension is its base unit.
+var units = []unit{
+	{symbol: "m", name: "metre", dimension: dimensionLength, factor: exact("1"), prefixes: siPrefixes},
+	{symbol: "in", name: "inch", dimension: dimensionLength, factor: exact("0.0254")},
+	{symbol: "ft", name: "foot", dimension: dimensionLength, factor: exact("0.3048")},
+	{symbol: "yd", name: "yard", dimension: dimensionLength, factor: exact("0.9144")},
+	{symbol: "mi", name: "mile", dimension: dimensionLength, factor: exact("1609.344")},
+	{symbol: "nmi", name: "nautical mile", dimension: dimensionLength, factor: exact("1852")},
+	{symbol: "au", name: "astronomical unit", dimension: dimensionLength, factor: exact("149597870700")},
+
+	{symbol: "g", name: "gram", dimension: dimensionMass, factor: exact("1"), prefixes: siPrefixes},
+	{symbol: "t", name: "tonne", dimension: dimensionMass, factor: exact("1e6")},
+	{symbol: "lb", name: "pound", dimension: dimensionMass, factor: exact("453.59237")},
+	{symbol: "oz", name: "ounce", dimension: dimensionMass, factor: exact("28.349523125")},
+	{symbol: "st", name: "stone", dimension: dimensionMass, factor: exact("6350.29318")},
+
+	{symbol: "s", name: "second", dimension: dimensionTime, factor: exact("1"), prefixes: siPrefixes},
+	{symbol: "min", name: "minute", dimension: dimensionTime, factor: exact("60")},
+	{symbol: "h", name: "hour", dimension: dimensionTime, factor: exact("3600")},
+	{symbol: "d", name: "day", dimension: dimensionTime, factor: exact("86400")},
+	{symbol: "wk", name: "week", dimension: dimensionTime, factor: exact("604800")},
+	{symbol: "yr", name: "julian year", dimension: dimensionTime, factor: exact("31557600")},
+
+	{symbol: "K", name: "kelvin", dimension: dimensionTemperature, factor: exact("1")},
+	{symbol: "C", name: "degree celsius", dimension: dimensionTemperature, factor: exact("1"), offset: exact("273.15")},
+	{symbol: "F", name: "degree fahrenheit", dimension: dimensionTemperature, factor: exact("5/9"), offset: exact("45967/180")},
+	{symbol: "R", name: "degree rankine", dimension: dimensionTemperature, factor: exact("5/9")},
+
+	{symbol: "B", name: "byte", dimension: dimensionData, factor: exact("1"), prefixes: dataPrefixes},
+	{symbol: "bit", name: "bit", dimension: dimensionData, factor: exact("0.125"), prefixes: dataPrefixes},
+
+	{symbol: "m/s", name: "metre per second", dimension: dimensionSpeed, factor: exact("1")},
+	{symbol: "km/h", name: "kilometre per hour", dimension: dimensionSpeed, factor: exact("1000/3600")},
+	{symbol: "mph", name: "mile per hour", dimension: dimensionSpeed, factor: exact("1609344/3600000")},
+	{symbol: "kn", name: "knot", dimension: dimensionSpeed, factor: exact("1852/3600")},
+	{symbol: "ft/s", name: "foot per second", dimension: dimensionSpeed, factor: exact("0.3048")},
+	{symbol: "c", name: "speed of light", dimension: dimensionSpeed, factor: exact("299792458")},
+}
+
+// unitsByName indexes the units by symbol and by lower case name.
+var unitsByName = func() map[string]*unit {
+	index := make(map[string]*unit)
+	for i := range units {
+		u := &units[i]
+		index[u.symbol] = u
+		index[strings.ToLower(u.name)] = u
+	}
+	return index
+}()
+
+// lookupUnit resolves a symbol ("km", "MiB") or a name ("kilometre"). Plain
+// units take precedence, so "ft" is a foot rather than a femtotonne.
+func lookupUnit(name string) (*unit, error) {
+	if u, ok := unitsByName[name]; ok {
+		return u, nil
+	}
+	if u, ok := unitsByName[strings.ToLower(name)]; ok && strings.ToLower(name) == u.name {
+		return u, nil
+	}
+
+	for i := range units {
+		base := &units[i]
+		for _, p := range base.prefixes {
+			if name == p.symbol+base.symbol || strings.ToLower(name) == p.name+base.name {
+				prefixed := *base
+				prefixed.symbol = p.symbol + base.symbol
+				prefixed.name = p.name + base.name
+				prefixed.factor = new(big.Rat).Mul(base.factor, p.factor)
+				prefixed.prefixes = nil
+				return &prefixed, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("unknown unit %q", name)
+}
+
+// convert converts value from one unit to another of the same dimension.
+func convert(value float64, from, to string) (float64, string, error) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, "", fmt.Errorf("value must be finite")
+	}
+	fromUnit, err := lookupUnit(from)
+	if err != nil {
+		return 0, "", err
+	}
+	toUnit, err := lookupUnit(to)
+	if err != nil {
+		return 0, "", err
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return 0, "", fmt.Errorf("cannot convert %v (%v) to %v (%v)", fromUnit.symbol, fromUnit.dimension, toUnit.symbol, toUnit.dimension)
+	}
+
+	base := new(big.Rat).SetFloat64(value)
+	base.Mul(base, fromUnit.factor)
+	if fromUnit.offset != nil {
+		base.Add(base, fromUnit.offset)
+	}
+	if fromUnit.dimension == dimensionTemperature && base.Sign() < 0 {
+		return 0, "", fmt.Errorf("%v %v is below absolute zero", value, fromUnit.symbol)
+	}
+	if toUnit.offset != nil {
+		base.Sub(base, toUnit.offset)
+	}
+	result, _ := base.Quo(base, toUnit.factor).Float64()
+	if math.IsInf(result, 0) {
+		return 0, "", fmt.Errorf("result overflows")
+	}
+	return result, fromUnit.dimension, nil
+}
+
+// unitsOf returns the units of dimension, or of every dimension when it is empty.
+func unitsOf(dimension string) ([]unit, error) {
+	known := dimension == ""
+	for _, d := range dimensions {
+		known = known || d == dimension
+	}
+	if !known {
+		return nil, fmt.Errorf("unknown dimension %q, expected one of %v", dimension, strings.Join(dimensions, ", "))
+	}
+	var result []unit
+	for _, u := range units {
+		if dimension == "" || u.dimension == dimension {
+			result = append(result, u)
+		}
+	}
+	return result, nil
+}
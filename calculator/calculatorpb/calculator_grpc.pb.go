@@ -23,17 +23,61 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CalculatorServiceClient interface {
 	// Unary
+	// Returns OUT_OF_RANGE if the sum overflows an int64 in CHECKED mode.
 	Sum(ctx context.Context, in *SumRequest, opts ...grpc.CallOption) (*SumResponse, error)
 	// Server Streaming
+	// Streams every distinct prime factor once with its multiplicity.
+	// Returns INVALID_ARGUMENT for numbers below 1.
 	PrimeNumberDecomposition(ctx context.Context, in *PrimeNumberDecompositionRequest, opts ...grpc.CallOption) (CalculatorService_PrimeNumberDecompositionClient, error)
 	// Client Streaming
+	// Both return INVALID_ARGUMENT if the client sends no number.
 	ComputeAverage(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_ComputeAverageClient, error)
+	// Median and percentiles are approximated with a bounded-memory sketch.
+	ComputeStatistics(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_ComputeStatisticsClient, error)
 	// BiDi Streaming
 	FindMaximum(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_FindMaximumClient, error)
+	// Sends the aggregated result every time it changes, including when values leave a time window.
+	// Returns INVALID_ARGUMENT for an incomplete or too large configuration.
+	Aggregate(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_AggregateClient, error)
 	// Error Handling
 	// This RPC will throw an exception if the sent number is negative.
 	// The error being sent is of type INVALID_ARGUMENT
 	SquareRoot(ctx context.Context, in *SquareRootRequest, opts ...grpc.CallOption) (*SquareRootResponse, error)
+	// Generalized root, see RootMode for how negative radicands are handled.
+	Root(ctx context.Context, in *RootRequest, opts ...grpc.CallOption) (*RootResponse, error)
+	// Expression Evaluation
+	// Supports + - * / % ^, parentheses, unary minus, functions and named constants.
+	// Syntax and evaluation errors are returned in the response with their position.
+	// Expressions that are too long or nested too deeply return INVALID_ARGUMENT.
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
+	// BiDi Streaming Session
+	// Every statement is answered with its result or error. Variables live in the session,
+	// which can be resumed by id after a reconnect until it has been idle for too long.
+	// Returns NOT_FOUND when resuming an unknown or expired session, DEADLINE_EXCEEDED when
+	// the stream stays idle and ABORTED when another stream resumes the same session.
+	CalculatorSession(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_CalculatorSessionClient, error)
+	// Primes
+	// IsPrime is deterministic below 2^64 and probabilistic with the stated certainty above.
+	IsPrime(ctx context.Context, in *IsPrimeRequest, opts ...grpc.CallOption) (*IsPrimeResponse, error)
+	// Returns the smallest prime greater than the number.
+	NextPrime(ctx context.Context, in *NextPrimeRequest, opts ...grpc.CallOption) (*NextPrimeResponse, error)
+	// Streams the primes in [from, to] in batches using a segmented sieve.
+	// Returns INVALID_ARGUMENT if to is larger than 10^14.
+	GeneratePrimes(ctx context.Context, in *GeneratePrimesRequest, opts ...grpc.CallOption) (CalculatorService_GeneratePrimesClient, error)
+	// Linear Algebra
+	// Matrices are limited to 256 rows and columns. Mismatched dimensions, non-square
+	// matrices where a square one is needed and non-finite values return INVALID_ARGUMENT.
+	AddMatrices(ctx context.Context, in *AddMatricesRequest, opts ...grpc.CallOption) (*AddMatricesResponse, error)
+	MultiplyMatrices(ctx context.Context, in *MultiplyMatricesRequest, opts ...grpc.CallOption) (*MultiplyMatricesResponse, error)
+	Transpose(ctx context.Context, in *TransposeRequest, opts ...grpc.CallOption) (*TransposeResponse, error)
+	Determinant(ctx context.Context, in *DeterminantRequest, opts ...grpc.CallOption) (*DeterminantResponse, error)
+	// Inverse and SolveLinearSystem return FAILED_PRECONDITION for a singular matrix.
+	Inverse(ctx context.Context, in *InverseRequest, opts ...grpc.CallOption) (*InverseResponse, error)
+	SolveLinearSystem(ctx context.Context, in *SolveLinearSystemRequest, opts ...grpc.CallOption) (*SolveLinearSystemResponse, error)
+	// Unit Conversion
+	// Returns INVALID_ARGUMENT for unknown units and units of different dimensions.
+	Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error)
+	ListUnits(ctx context.Context, in *ListUnitsRequest, opts ...grpc.CallOption) (*ListUnitsResponse, error)
 }
 
 type calculatorServiceClient struct {
@@ -119,8 +163,42 @@ func (x *calculatorServiceComputeAverageClient) CloseAndRecv() (*ComputeAverageR
 	return m, nil
 }
 
+func (c *calculatorServiceClient) ComputeStatistics(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_ComputeStatisticsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[2], "/calculator.CalculatorService/ComputeStatistics", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calculatorServiceComputeStatisticsClient{stream}
+	return x, nil
+}
+
+type CalculatorService_ComputeStatisticsClient interface {
+	Send(*ComputeStatisticsRequest) error
+	CloseAndRecv() (*ComputeStatisticsResponse, error)
+	grpc.ClientStream
+}
+
+type calculatorServiceComputeStatisticsClient struct {
+	grpc.ClientStream
+}
+
+func (x *calculatorServiceComputeStatisticsClient) Send(m *ComputeStatisticsRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *calculatorServiceComputeStatisticsClient) CloseAndRecv() (*ComputeStatisticsResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ComputeStatisticsResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *calculatorServiceClient) FindMaximum(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_FindMaximumClient, error) {
-	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[2], "/calculator.CalculatorService/FindMaximum", opts...)
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[3], "/calculator.CalculatorService/FindMaximum", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +228,37 @@ func (x *calculatorServiceFindMaximumClient) Recv() (*FindMaximumResponse, error
 	return m, nil
 }
 
+func (c *calculatorServiceClient) Aggregate(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_AggregateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[4], "/calculator.CalculatorService/Aggregate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calculatorServiceAggregateClient{stream}
+	return x, nil
+}
+
+type CalculatorService_AggregateClient interface {
+	Send(*AggregateRequest) error
+	Recv() (*AggregateResponse, error)
+	grpc.ClientStream
+}
+
+type calculatorServiceAggregateClient struct {
+	grpc.ClientStream
+}
+
+func (x *calculatorServiceAggregateClient) Send(m *AggregateRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *calculatorServiceAggregateClient) Recv() (*AggregateResponse, error) {
+	m := new(AggregateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *calculatorServiceClient) SquareRoot(ctx context.Context, in *SquareRootRequest, opts ...grpc.CallOption) (*SquareRootResponse, error) {
 	out := new(SquareRootResponse)
 	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/SquareRoot", in, out, opts...)
@@ -159,22 +268,237 @@ func (c *calculatorServiceClient) SquareRoot(ctx context.Context, in *SquareRoot
 	return out, nil
 }
 
+func (c *calculatorServiceClient) Root(ctx context.Context, in *RootRequest, opts ...grpc.CallOption) (*RootResponse, error) {
+	out := new(RootResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Root", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Evaluate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) CalculatorSession(ctx context.Context, opts ...grpc.CallOption) (CalculatorService_CalculatorSessionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[5], "/calculator.CalculatorService/CalculatorSession", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calculatorServiceCalculatorSessionClient{stream}
+	return x, nil
+}
+
+type CalculatorService_CalculatorSessionClient interface {
+	Send(*CalculatorSessionRequest) error
+	Recv() (*CalculatorSessionResponse, error)
+	grpc.ClientStream
+}
+
+type calculatorServiceCalculatorSessionClient struct {
+	grpc.ClientStream
+}
+
+func (x *calculatorServiceCalculatorSessionClient) Send(m *CalculatorSessionRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *calculatorServiceCalculatorSessionClient) Recv() (*CalculatorSessionResponse, error) {
+	m := new(CalculatorSessionResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *calculatorServiceClient) IsPrime(ctx context.Context, in *IsPrimeRequest, opts ...grpc.CallOption) (*IsPrimeResponse, error) {
+	out := new(IsPrimeResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/IsPrime", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) NextPrime(ctx context.Context, in *NextPrimeRequest, opts ...grpc.CallOption) (*NextPrimeResponse, error) {
+	out := new(NextPrimeResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/NextPrime", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) GeneratePrimes(ctx context.Context, in *GeneratePrimesRequest, opts ...grpc.CallOption) (CalculatorService_GeneratePrimesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CalculatorService_ServiceDesc.Streams[6], "/calculator.CalculatorService/GeneratePrimes", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &calculatorServiceGeneratePrimesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CalculatorService_GeneratePrimesClient interface {
+	Recv() (*GeneratePrimesResponse, error)
+	grpc.ClientStream
+}
+
+type calculatorServiceGeneratePrimesClient struct {
+	grpc.ClientStream
+}
+
+func (x *calculatorServiceGeneratePrimesClient) Recv() (*GeneratePrimesResponse, error) {
+	m := new(GeneratePrimesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *calculatorServiceClient) AddMatrices(ctx context.Context, in *AddMatricesRequest, opts ...grpc.CallOption) (*AddMatricesResponse, error) {
+	out := new(AddMatricesResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/AddMatrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) MultiplyMatrices(ctx context.Context, in *MultiplyMatricesRequest, opts ...grpc.CallOption) (*MultiplyMatricesResponse, error) {
+	out := new(MultiplyMatricesResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/MultiplyMatrices", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) Transpose(ctx context.Context, in *TransposeRequest, opts ...grpc.CallOption) (*TransposeResponse, error) {
+	out := new(TransposeResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Transpose", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) Determinant(ctx context.Context, in *DeterminantRequest, opts ...grpc.CallOption) (*DeterminantResponse, error) {
+	out := new(DeterminantResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Determinant", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) Inverse(ctx context.Context, in *InverseRequest, opts ...grpc.CallOption) (*InverseResponse, error) {
+	out := new(InverseResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Inverse", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) SolveLinearSystem(ctx context.Context, in *SolveLinearSystemRequest, opts ...grpc.CallOption) (*SolveLinearSystemResponse, error) {
+	out := new(SolveLinearSystemResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/SolveLinearSystem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) Convert(ctx context.Context, in *ConvertRequest, opts ...grpc.CallOption) (*ConvertResponse, error) {
+	out := new(ConvertResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/Convert", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *calculatorServiceClient) ListUnits(ctx context.Context, in *ListUnitsRequest, opts ...grpc.CallOption) (*ListUnitsResponse, error) {
+	out := new(ListUnitsResponse)
+	err := c.cc.Invoke(ctx, "/calculator.CalculatorService/ListUnits", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CalculatorServiceServer is the server API for CalculatorService service.
 // All implementations must embed UnimplementedCalculatorServiceServer
 // for forward compatibility
 type CalculatorServiceServer interface {
 	// Unary
+	// Returns OUT_OF_RANGE if the sum overflows an int64 in CHECKED mode.
 	Sum(context.Context, *SumRequest) (*SumResponse, error)
 	// Server Streaming
+	// Streams every distinct prime factor once with its multiplicity.
+	// Returns INVALID_ARGUMENT for numbers below 1.
 	PrimeNumberDecomposition(*PrimeNumberDecompositionRequest, CalculatorService_PrimeNumberDecompositionServer) error
 	// Client Streaming
+	// Both return INVALID_ARGUMENT if the client sends no number.
 	ComputeAverage(CalculatorService_ComputeAverageServer) error
+	// Median and percentiles are approximated with a bounded-memory sketch.
+	ComputeStatistics(CalculatorService_ComputeStatisticsServer) error
 	// BiDi Streaming
 	FindMaximum(CalculatorService_FindMaximumServer) error
+	// Sends the aggregated result every time it changes, including when values leave a time window.
+	// Returns INVALID_ARGUMENT for an incomplete or too large configuration.
+	Aggregate(CalculatorService_AggregateServer) error
 	// Error Handling
 	// This RPC will throw an exception if the sent number is negative.
 	// The error being sent is of type INVALID_ARGUMENT
 	SquareRoot(context.Context, *SquareRootRequest) (*SquareRootResponse, error)
+	// Generalized root, see RootMode for how negative radicands are handled.
+	Root(context.Context, *RootRequest) (*RootResponse, error)
+	// Expression Evaluation
+	// Supports + - * / % ^, parentheses, unary minus, functions and named constants.
+	// Syntax and evaluation errors are returned in the response with their position.
+	// Expressions that are too long or nested too deeply return INVALID_ARGUMENT.
+	Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
+	// BiDi Streaming Session
+	// Every statement is answered with its result or error. Variables live in the session,
+	// which can be resumed by id after a reconnect until it has been idle for too long.
+	// Returns NOT_FOUND when resuming an unknown or expired session, DEADLINE_EXCEEDED when
+	// the stream stays idle and ABORTED when another stream resumes the same session.
+	CalculatorSession(CalculatorService_CalculatorSessionServer) error
+	// Primes
+	// IsPrime is deterministic below 2^64 and probabilistic with the stated certainty above.
+	IsPrime(context.Context, *IsPrimeRequest) (*IsPrimeResponse, error)
+	// Returns the smallest prime greater than the number.
+	NextPrime(context.Context, *NextPrimeRequest) (*NextPrimeResponse, error)
+	// Streams the primes in [from, to] in batches using a segmented sieve.
+	// Returns INVALID_ARGUMENT if to is larger than 10^14.
+	GeneratePrimes(*GeneratePrimesRequest, CalculatorService_GeneratePrimesServer) error
+	// Linear Algebra
+	// Matrices are limited to 256 rows and columns. Mismatched dimensions, non-square
+	// matrices where a square one is needed and non-finite values return INVALID_ARGUMENT.
+	AddMatrices(context.Context, *AddMatricesRequest) (*AddMatricesResponse, error)
+	MultiplyMatrices(context.Context, *MultiplyMatricesRequest) (*MultiplyMatricesResponse, error)
+	Transpose(context.Context, *TransposeRequest) (*TransposeResponse, error)
+	Determinant(context.Context, *DeterminantRequest) (*DeterminantResponse, error)
+	// Inverse and SolveLinearSystem return FAILED_PRECONDITION for a singular matrix.
+	Inverse(context.Context, *InverseRequest) (*InverseResponse, error)
+	SolveLinearSystem(context.Context, *SolveLinearSystemRequest) (*SolveLinearSystemResponse, error)
+	// Unit Conversion
+	// Returns INVALID_ARGUMENT for unknown units and units of different dimensions.
+	Convert(context.Context, *ConvertRequest) (*ConvertResponse, error)
+	ListUnits(context.Context, *ListUnitsRequest) (*ListUnitsResponse, error)
 	mustEmbedUnimplementedCalculatorServiceServer()
 }
 
@@ -191,12 +515,60 @@ func (UnimplementedCalculatorServiceServer) PrimeNumberDecomposition(*PrimeNumbe
 func (UnimplementedCalculatorServiceServer) ComputeAverage(CalculatorService_ComputeAverageServer) error {
 	return status.Errorf(codes.Unimplemented, "method ComputeAverage not implemented")
 }
+func (UnimplementedCalculatorServiceServer) ComputeStatistics(CalculatorService_ComputeStatisticsServer) error {
+	return status.Errorf(codes.Unimplemented, "method ComputeStatistics not implemented")
+}
 func (UnimplementedCalculatorServiceServer) FindMaximum(CalculatorService_FindMaximumServer) error {
 	return status.Errorf(codes.Unimplemented, "method FindMaximum not implemented")
 }
+func (UnimplementedCalculatorServiceServer) Aggregate(CalculatorService_AggregateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Aggregate not implemented")
+}
 func (UnimplementedCalculatorServiceServer) SquareRoot(context.Context, *SquareRootRequest) (*SquareRootResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SquareRoot not implemented")
 }
+func (UnimplementedCalculatorServiceServer) Root(context.Context, *RootRequest) (*RootResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Root not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedCalculatorServiceServer) CalculatorSession(CalculatorService_CalculatorSessionServer) error {
+	return status.Errorf(codes.Unimplemented, "method CalculatorSession not implemented")
+}
+func (UnimplementedCalculatorServiceServer) IsPrime(context.Context, *IsPrimeRequest) (*IsPrimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsPrime not implemented")
+}
+func (UnimplementedCalculatorServiceServer) NextPrime(context.Context, *NextPrimeRequest) (*NextPrimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NextPrime not implemented")
+}
+func (UnimplementedCalculatorServiceServer) GeneratePrimes(*GeneratePrimesRequest, CalculatorService_GeneratePrimesServer) error {
+	return status.Errorf(codes.Unimplemented, "method GeneratePrimes not implemented")
+}
+func (UnimplementedCalculatorServiceServer) AddMatrices(context.Context, *AddMatricesRequest) (*AddMatricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMatrices not implemented")
+}
+func (UnimplementedCalculatorServiceServer) MultiplyMatrices(context.Context, *MultiplyMatricesRequest) (*MultiplyMatricesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MultiplyMatrices not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Transpose(context.Context, *TransposeRequest) (*TransposeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Transpose not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Determinant(context.Context, *DeterminantRequest) (*DeterminantResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Determinant not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Inverse(context.Context, *InverseRequest) (*InverseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Inverse not implemented")
+}
+func (UnimplementedCalculatorServiceServer) SolveLinearSystem(context.Context, *SolveLinearSystemRequest) (*SolveLinearSystemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SolveLinearSystem not implemented")
+}
+func (UnimplementedCalculatorServiceServer) Convert(context.Context, *ConvertRequest) (*ConvertResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Convert not implemented")
+}
+func (UnimplementedCalculatorServiceServer) ListUnits(context.Context, *ListUnitsRequest) (*ListUnitsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUnits not implemented")
+}
 func (UnimplementedCalculatorServiceServer) mustEmbedUnimplementedCalculatorServiceServer() {}
 
 // UnsafeCalculatorServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -275,6 +647,32 @@ func (x *calculatorServiceComputeAverageServer) Recv() (*ComputeAverageRequest,
 	return m, nil
 }
 
+func _CalculatorService_ComputeStatistics_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).ComputeStatistics(&calculatorServiceComputeStatisticsServer{stream})
+}
+
+type CalculatorService_ComputeStatisticsServer interface {
+	SendAndClose(*ComputeStatisticsResponse) error
+	Recv() (*ComputeStatisticsRequest, error)
+	grpc.ServerStream
+}
+
+type calculatorServiceComputeStatisticsServer struct {
+	grpc.ServerStream
+}
+
+func (x *calculatorServiceComputeStatisticsServer) SendAndClose(m *ComputeStatisticsResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *calculatorServiceComputeStatisticsServer) Recv() (*ComputeStatisticsRequest, error) {
+	m := new(ComputeStatisticsRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _CalculatorService_FindMaximum_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(CalculatorServiceServer).FindMaximum(&calculatorServiceFindMaximumServer{stream})
 }
@@ -301,6 +699,32 @@ func (x *calculatorServiceFindMaximumServer) Recv() (*FindMaximumRequest, error)
 	return m, nil
 }
 
+func _CalculatorService_Aggregate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).Aggregate(&calculatorServiceAggregateServer{stream})
+}
+
+type CalculatorService_AggregateServer interface {
+	Send(*AggregateResponse) error
+	Recv() (*AggregateRequest, error)
+	grpc.ServerStream
+}
+
+type calculatorServiceAggregateServer struct {
+	grpc.ServerStream
+}
+
+func (x *calculatorServiceAggregateServer) Send(m *AggregateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *calculatorServiceAggregateServer) Recv() (*AggregateRequest, error) {
+	m := new(AggregateRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _CalculatorService_SquareRoot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(SquareRootRequest)
 	if err := dec(in); err != nil {
@@ -319,6 +743,269 @@ func _CalculatorService_SquareRoot_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CalculatorService_Root_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RootRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Root(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Root",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Root(ctx, req.(*RootRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Evaluate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_CalculatorSession_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CalculatorServiceServer).CalculatorSession(&calculatorServiceCalculatorSessionServer{stream})
+}
+
+type CalculatorService_CalculatorSessionServer interface {
+	Send(*CalculatorSessionResponse) error
+	Recv() (*CalculatorSessionRequest, error)
+	grpc.ServerStream
+}
+
+type calculatorServiceCalculatorSessionServer struct {
+	grpc.ServerStream
+}
+
+func (x *calculatorServiceCalculatorSessionServer) Send(m *CalculatorSessionResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *calculatorServiceCalculatorSessionServer) Recv() (*CalculatorSessionRequest, error) {
+	m := new(CalculatorSessionRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CalculatorService_IsPrime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsPrimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).IsPrime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/IsPrime",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).IsPrime(ctx, req.(*IsPrimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_NextPrime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NextPrimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).NextPrime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/NextPrime",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).NextPrime(ctx, req.(*NextPrimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_GeneratePrimes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GeneratePrimesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CalculatorServiceServer).GeneratePrimes(m, &calculatorServiceGeneratePrimesServer{stream})
+}
+
+type CalculatorService_GeneratePrimesServer interface {
+	Send(*GeneratePrimesResponse) error
+	grpc.ServerStream
+}
+
+type calculatorServiceGeneratePrimesServer struct {
+	grpc.ServerStream
+}
+
+func (x *calculatorServiceGeneratePrimesServer) Send(m *GeneratePrimesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CalculatorService_AddMatrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMatricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).AddMatrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/AddMatrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).AddMatrices(ctx, req.(*AddMatricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_MultiplyMatrices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MultiplyMatricesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).MultiplyMatrices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/MultiplyMatrices",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).MultiplyMatrices(ctx, req.(*MultiplyMatricesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_Transpose_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransposeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Transpose(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Transpose",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Transpose(ctx, req.(*TransposeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_Determinant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeterminantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Determinant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Determinant",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Determinant(ctx, req.(*DeterminantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_Inverse_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InverseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Inverse(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Inverse",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Inverse(ctx, req.(*InverseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_SolveLinearSystem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SolveLinearSystemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).SolveLinearSystem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/SolveLinearSystem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).SolveLinearSystem(ctx, req.(*SolveLinearSystemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_Convert_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConvertRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).Convert(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/Convert",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).Convert(ctx, req.(*ConvertRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CalculatorService_ListUnits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUnitsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CalculatorServiceServer).ListUnits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/calculator.CalculatorService/ListUnits",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CalculatorServiceServer).ListUnits(ctx, req.(*ListUnitsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CalculatorService_ServiceDesc is the grpc.ServiceDesc for CalculatorService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -334,6 +1021,54 @@ var CalculatorService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SquareRoot",
 			Handler:    _CalculatorService_SquareRoot_Handler,
 		},
+		{
+			MethodName: "Root",
+			Handler:    _CalculatorService_Root_Handler,
+		},
+		{
+			MethodName: "Evaluate",
+			Handler:    _CalculatorService_Evaluate_Handler,
+		},
+		{
+			MethodName: "IsPrime",
+			Handler:    _CalculatorService_IsPrime_Handler,
+		},
+		{
+			MethodName: "NextPrime",
+			Handler:    _CalculatorService_NextPrime_Handler,
+		},
+		{
+			MethodName: "AddMatrices",
+			Handler:    _CalculatorService_AddMatrices_Handler,
+		},
+		{
+			MethodName: "MultiplyMatrices",
+			Handler:    _CalculatorService_MultiplyMatrices_Handler,
+		},
+		{
+			MethodName: "Transpose",
+			Handler:    _CalculatorService_Transpose_Handler,
+		},
+		{
+			MethodName: "Determinant",
+			Handler:    _CalculatorService_Determinant_Handler,
+		},
+		{
+			MethodName: "Inverse",
+			Handler:    _CalculatorService_Inverse_Handler,
+		},
+		{
+			MethodName: "SolveLinearSystem",
+			Handler:    _CalculatorService_SolveLinearSystem_Handler,
+		},
+		{
+			MethodName: "Convert",
+			Handler:    _CalculatorService_Convert_Handler,
+		},
+		{
+			MethodName: "ListUnits",
+			Handler:    _CalculatorService_ListUnits_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -346,12 +1081,34 @@ var CalculatorService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _CalculatorService_ComputeAverage_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "ComputeStatistics",
+			Handler:       _CalculatorService_ComputeStatistics_Handler,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "FindMaximum",
 			Handler:       _CalculatorService_FindMaximum_Handler,
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Aggregate",
+			Handler:       _CalculatorService_Aggregate_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "CalculatorSession",
+			Handler:       _CalculatorService_CalculatorSession_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "GeneratePrimes",
+			Handler:       _CalculatorService_GeneratePrimes_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "calculator/calculatorpb/calculator.proto",
 }
@@ -2,34 +2,54 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
 	"fmt"
 	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"io"
 	"log"
+	"os"
+	"sync"
 	"time"
 )
 
 const (
 	serverPort = 50051
-	tls        = true
+
+	// Must match the -admin-token flag of greet_server, which refuses template changes without one
+	adminToken = "greet-admin-token"
+)
+
+var (
+	// Must match the -tls flag of greet_server
+	tlsMode  = flag.String("tls", "tls", "Transport security: none, tls, or mtls to also present a client certificate")
+	caFile   = flag.String("ca", "ssl/ca.crt", "Certificate Authority trusted to sign the server certificate")
+	certFile = flag.String("cert", "ssl/client.crt", "Client certificate presented in mtls mode")
+	keyFile  = flag.String("key", "ssl/client.pem", "Private key of the client certificate")
 )
 
 func main() {
+	flag.Parse()
 	fmt.Println("Hello, I'm a client")
 
 	creds := insecure.NewCredentials()
-	if tls {
-		certFile := "ssl/ca.crt" // Certificate Authority Trust
-		credsBuffer, sslErr := credentials.NewClientTLSFromFile(certFile, "")
-		if sslErr != nil {
-			log.Fatalf("Error while loading CA trust certificate: %v", sslErr)
+	switch *tlsMode {
+	case "none":
+	case "tls", "mtls":
+		config, err := clientTLSConfig(*tlsMode == "mtls")
+		if err != nil {
+			log.Fatalf("Error while loading certificates: %v", err)
 		}
-		creds = credsBuffer
+		creds = credentials.NewTLS(config)
+	default:
+		log.Fatalf("Invalid -tls mode %q, use none, tls or mtls", *tlsMode)
 	}
 
 	opts := grpc.WithTransportCredentials(creds)
@@ -48,13 +68,40 @@ func main() {
 	//fmt.Printf("Created client: %f\n", c)
 
 	doUnary(c)
+	//doLocalizedUnary(c)
 	//doServerStreaming(c)
 	//doClientStreaming(c)
+	//doClientStreamingWithAcks(c)
 	//doBiDiStreaming(c)
+	//doChat(c)
+	//doPresence(c)
+	//doTemplates(cc, c)
 	//doUnaryWithDeadline(c, 5*time.Second) // should complete
 	//doUnaryWithDeadline(c, 1*time.Second) // should timeout
 }
 
+// clientTLSConfig trusts the server certificates signed by caFile and, when
+// mutual, presents the certificate of certFile and keyFile to the server.
+func clientTLSConfig(mutual bool) (*tls.Config, error) {
+	pem, err := os.ReadFile(*caFile)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", *caFile)
+	}
+	config := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if mutual {
+		cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
 func doUnary(c greetpb.GreetServiceClient) {
 	fmt.Println("Starting to do a Unary RPC...")
 	req := &greetpb.GreetRequest{
@@ -70,6 +117,76 @@ func doUnary(c greetpb.GreetServiceClient) {
 	log.Printf("Response from Greet: %v", res.Result)
 }
 
+func doLocalizedUnary(c greetpb.GreetServiceClient) {
+	fmt.Println("Starting to do localized Unary RPCs...")
+	greetings := [...]*greetpb.Greeting{
+		{FirstName: "Stephane", LastName: "Marek", Locale: "fr-CA"},
+		{FirstName: "Stephane", LastName: "Marek", Locale: "de", Honorific: "dr"},
+		{FirstName: "Yuki", LastName: "Tanaka", Locale: "ja"},
+		{FirstName: "Stephane", LastName: "Marek"}, // uses the accept-language header
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "accept-language", "es-MX, es;q=0.9, en;q=0.5")
+	for _, greeting := range greetings {
+		res, err := c.Greet(ctx, &greetpb.GreetRequest{Greeting: greeting})
+		if err != nil {
+			log.Fatalf("Error while calling Greet Unary RPC: %v", err)
+		}
+		log.Printf("Response from Greet: %v", res.Result)
+	}
+}
+
+func doTemplates(cc *grpc.ClientConn, c greetpb.GreetServiceClient) {
+	fmt.Println("Starting to do the GreetingTemplateService RPCs...")
+	templates := greetpb.NewGreetingTemplateServiceClient(cc)
+	admin := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+adminToken)
+
+	// Invalid template
+	_, err := templates.CreateTemplate(admin, &greetpb.CreateTemplateRequest{
+		Template: &greetpb.GreetingTemplate{Name: "broken", Body: "Hi {{.Nickname}}"},
+	})
+	if err != nil {
+		respErr, _ := status.FromError(err)
+		fmt.Printf("Error message from server: %v (%v)\n", respErr.Message(), respErr.Code())
+	}
+
+	template := &greetpb.GreetingTemplate{
+		Name: "welcome",
+		Body: "Welcome back, {{.FirstName}}{{with .LastName}} {{.}}{{end}}!",
+	}
+	_, err = templates.CreateTemplate(admin, &greetpb.CreateTemplateRequest{Template: template})
+	if status.Code(err) == codes.AlreadyExists {
+		_, err = templates.UpdateTemplate(admin, &greetpb.UpdateTemplateRequest{Template: template})
+	}
+	if err != nil {
+		log.Fatalf("Error while saving the template: %v", err)
+	}
+
+	res, err := c.Greet(context.Background(), &greetpb.GreetRequest{
+		Greeting: &greetpb.Greeting{FirstName: "Stephane", LastName: "Marek"},
+		Template: "welcome",
+	})
+	if err != nil {
+		log.Fatalf("Error while calling Greet Unary RPC: %v", err)
+	}
+	log.Printf("Response from Greet: %v", res.Result)
+
+	stream, err := templates.ListTemplate(context.Background(), &greetpb.ListTemplateRequest{})
+	if err != nil {
+		log.Fatalf("Error while calling ListTemplate RPC: %v", err)
+	}
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error while reading stream %v", err)
+		}
+		fmt.Printf("Template %v: %v\n", res.GetTemplate().GetName(), res.GetTemplate().GetBody())
+	}
+}
+
 func doServerStreaming(c greetpb.GreetServiceClient) {
 	fmt.Println("Starting to do a Server Streaming RPC")
 
@@ -78,6 +195,8 @@ func doServerStreaming(c greetpb.GreetServiceClient) {
 			FirstName: "Stephane",
 			LastName:  "Mareek",
 		},
+		Count:          5,
+		IntervalMillis: 500,
 	}
 	resStream, err := c.GreetManyTimes(context.Background(), req)
 	if err != nil {
@@ -91,7 +210,8 @@ func doServerStreaming(c greetpb.GreetServiceClient) {
 		if err != nil {
 			log.Fatalf("Error while reading stream %v", err)
 		}
-		log.Printf("Response from GreetManyTimes: %v", res.GetResult())
+		sentAt := time.Unix(0, res.GetSentAtUnixMillis()*int64(time.Millisecond))
+		log.Printf("Response %v from GreetManyTimes sent at %v: %v", res.GetSequence(), sentAt.Format(time.StampMilli), res.GetResult())
 	}
 }
 
@@ -142,6 +262,47 @@ func doClientStreaming(c greetpb.GreetServiceClient) {
 	fmt.Printf("LongGreet response: %v\n", res)
 }
 
+func doClientStreamingWithAcks(c greetpb.GreetServiceClient) {
+	fmt.Println("Starting to do a LongGreetWithAcks BiDi Streaming RPC...")
+
+	stream, err := c.LongGreetWithAcks(context.Background())
+	if err != nil {
+		log.Fatalf("Error while calling LongGreetWithAcks: %v", err)
+	}
+
+	// Sender, John is greeted twice
+	go func() {
+		for _, name := range [...]string{"Stephane", "John", "Lucy", "John"} {
+			err := stream.Send(&greetpb.LongGreetRequest{Greeting: &greetpb.Greeting{FirstName: name}})
+			if err != nil {
+				log.Printf("Error while sending data to stream: %v\n", err)
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+		if err := stream.CloseSend(); err != nil {
+			log.Fatalf("Error while closing send stream: %v\n", err)
+		}
+	}()
+
+	// Receiver
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatalf("Error while receiving: %v\n", err)
+		}
+		if ack := event.GetAck(); ack != nil {
+			fmt.Printf("Ack %v: %v (duplicate: %v, count: %v)\n", ack.GetSequence(), ack.GetResult(), ack.GetDuplicate(), ack.GetCount())
+			continue
+		}
+		for _, greeted := range event.GetSummary().GetGreeted() {
+			fmt.Printf("Greeted %v %v time(s)\n", greeted.GetGreeting().GetFirstName(), greeted.GetCount())
+		}
+	}
+}
+
 func doBiDiStreaming(c greetpb.GreetServiceClient) {
 	fmt.Println("Starting to do a BiDi Streaming RPC...")
 
@@ -204,6 +365,125 @@ func doBiDiStreaming(c greetpb.GreetServiceClient) {
 	}
 }
 
+func doChat(c greetpb.GreetServiceClient) {
+	fmt.Println("Starting to do a Chat BiDi Streaming RPC...")
+
+	var wg sync.WaitGroup
+	for i, name := range [...]string{"Stephane", "John"} {
+		wg.Add(1)
+		go func(name string, delay time.Duration) {
+			defer wg.Done()
+			chat(c, "general", name, delay)
+		}(name, time.Duration(i)*500*time.Millisecond)
+	}
+	wg.Wait()
+}
+
+// chat joins room, says hello twice and leaves, printing every event of the room meanwhile.
+func chat(c greetpb.GreetServiceClient, room string, name string, delay time.Duration) {
+	time.Sleep(delay)
+	stream, err := c.Chat(context.Background())
+	if err != nil {
+		log.Fatalf("Error while creating stream: %v", err)
+	}
+
+	// Sender
+	go func() {
+		err := stream.Send(&greetpb.ChatRequest{
+			Greeting: &greetpb.Greeting{FirstName: name},
+			Room:     room,
+		})
+		if err != nil {
+			log.Printf("Error while sending data to stream: %v\n", err)
+		}
+		for i := 0; i < 2; i++ {
+			time.Sleep(1000 * time.Millisecond)
+			err := stream.Send(&greetpb.ChatRequest{Text: fmt.Sprintf("Hello everyone, this is %v!", name)})
+			if err != nil {
+				log.Printf("Error while sending data to stream: %v\n", err)
+			}
+		}
+		time.Sleep(1000 * time.Millisecond)
+		if err := stream.CloseSend(); err != nil {
+			log.Fatalf("Error while closing send stream: %v\n", err)
+		}
+	}()
+
+	// Receiver
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("Error while receiving: %v\n", err)
+			break
+		}
+		switch event.GetType() {
+		case greetpb.ChatEvent_MESSAGE:
+			fmt.Printf("[%v] %v #%v %v: %v\n", name, event.GetRoom(), event.GetSequence(), event.GetSender(), event.GetText())
+		case greetpb.ChatEvent_DROPPED:
+			fmt.Printf("[%v] %v: missed %v events\n", name, event.GetRoom(), event.GetDropped())
+		default:
+			fmt.Printf("[%v] %v #%v %v %v\n", name, event.GetRoom(), event.GetSequence(), event.GetSender(), event.GetType())
+		}
+	}
+}
+
+func doPresence(c greetpb.GreetServiceClient) {
+	fmt.Println("Starting to do the presence RPCs...")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := c.WatchPresence(ctx, &greetpb.WatchPresenceRequest{})
+	if err != nil {
+		log.Fatalf("Error while calling WatchPresence RPC: %v", err)
+	}
+	go func() {
+		for {
+			event, err := watch.Recv()
+			if err != nil {
+				return
+			}
+			fmt.Printf("Presence event: %v %v %v (snapshot: %v)\n",
+				event.GetType(), event.GetPresence().GetGreeting().GetFirstName(),
+				event.GetPresence().GetPeerAddress(), event.GetSnapshot())
+		}
+	}()
+
+	// A client is online while its GreetEveryone stream is open
+	stream, err := c.GreetEveryone(context.Background())
+	if err != nil {
+		log.Fatalf("Error while creating stream: %v", err)
+	}
+	err = stream.Send(&greetpb.GreetEveryoneRequest{
+		Greeting: &greetpb.Greeting{FirstName: "Stephane", LastName: "Marek"},
+	})
+	if err != nil {
+		log.Fatalf("Error while sending data to stream: %v\n", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		log.Fatalf("Error while receiving: %v\n", err)
+	}
+
+	res, err := c.ListPresence(context.Background(), &greetpb.ListPresenceRequest{})
+	if err != nil {
+		log.Fatalf("Error while calling ListPresence RPC: %v", err)
+	}
+	for _, presence := range res.GetPresences() {
+		fmt.Printf("Online: %v %v from %v with %v streams\n", presence.GetGreeting().GetFirstName(),
+			presence.GetGreeting().GetLastName(), presence.GetPeerAddress(), presence.GetStreams())
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		log.Fatalf("Error while closing send stream: %v\n", err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		log.Fatalf("Unexpected end of stream: %v\n", err)
+	}
+	time.Sleep(500 * time.Millisecond) // let the offline event arrive
+}
+
 func doUnaryWithDeadline(c greetpb.GreetServiceClient, timeout time.Duration) {
 	log.Println("Starting to do a UnaryWithDeadline RPC...")
 	req := &greetpb.GreetWithDeadlineRequest{
@@ -215,7 +495,9 @@ func doUnaryWithDeadline(c greetpb.GreetServiceClient, timeout time.Duration) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	res, err := c.GreetWithDeadline(ctx, req)
+	var trailer metadata.MD
+	res, err := c.GreetWithDeadline(ctx, req, grpc.Trailer(&trailer))
+	log.Printf("Deadline budget: %vms, remaining: %vms", trailer.Get("deadline-budget-ms"), trailer.Get("deadline-remaining-ms"))
 	if err != nil {
 		statusErr, ok := status.FromError(err)
 
@@ -226,7 +508,7 @@ func doUnaryWithDeadline(c greetpb.GreetServiceClient, timeout time.Duration) {
 
 		// gRPC error
 		if statusErr.Code() == codes.DeadlineExceeded {
-			log.Fatalf("Timeout was hit! Deadline was exceeded")
+			log.Fatalf("Timeout was hit! Deadline was exceeded: %v", statusErr.Message())
 		}
 		log.Fatalf("Unexpected gRPC error: %v\n", statusErr)
 	}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errNoCertificate = errors.New("no server certificate loaded")
+
+// The active certificate is exposed on /debug/vars when the debug server is enabled.
+var activeCertificate = expvar.NewMap("greet_certificate")
+
+// certificateLoader serves the certificate pair of certFile and keyFile to
+// new TLS handshakes. Reloading replaces it atomically, and keeps the previous
+// pair when the new one cannot be loaded.
+type certificateLoader struct {
+	certFile, keyFile string
+	onReload          func(err error) // called after every load attempt
+
+	mu      sync.Mutex // serializes reloads
+	stamps  [2]fileStamp
+	current atomic.Value
+}
+
+// fileStamp identifies a version of a file well enough to notice it was replaced.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newCertificateLoader(certFile, keyFile string, onReload func(err error)) *certificateLoader {
+	return &certificateLoader{certFile: certFile, keyFile: keyFile, onReload: onReload}
+}
+
+func (l *certificateLoader) reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Remember what was attempted, a failed pair is retried once the files change again
+	l.stamps = l.stampFiles()
+	cert, err := l.load()
+	if err != nil {
+		if l.current.Load() != nil {
+			log.Printf("Failed reloading certificates, keeping the previous ones: %v", err)
+		} else {
+			log.Printf("Failed loading certificates: %v", err)
+		}
+		l.onReload(err)
+		return err
+	}
+	l.current.Store(cert)
+
+	leaf := cert.Leaf
+	log.Printf("Loaded certificates from %v and %v: %v, valid until %v", l.certFile, l.keyFile, leaf.Subject, leaf.NotAfter)
+	if remaining := time.Until(leaf.NotAfter); remaining < 0 {
+		log.Printf("The certificate of %v has expired", leaf.Subject)
+	} else if remaining < 7*24*time.Hour {
+		log.Printf("The certificate of %v expires in %v", leaf.Subject, remaining.Round(time.Minute))
+	}
+	activeCertificate.Set("subject", expvarString(leaf.Subject.String()))
+	activeCertificate.Set("not_after", expvarString(leaf.NotAfter.Format(time.RFC3339)))
+	activeCertificate.Set("loaded_at", expvarString(time.Now().Format(time.RFC3339)))
+	l.onReload(nil)
+	return nil
+}
+
+func (l *certificateLoader) load() (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	// Parsed once here rather than on every handshake
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func (l *certificateLoader) stampFiles() [2]fileStamp {
+	var stamps [2]fileStamp
+	for i, path := range []string{l.certFile, l.keyFile} {
+		if info, err := os.Stat(path); err == nil {
+			stamps[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+// watch reloads the certificates whenever one of the files changes, checking
+// every interval.
+func (l *certificateLoader) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		l.mu.Lock()
+		changed := l.stampFiles() != l.stamps
+		l.mu.Unlock()
+		if changed {
+			l.reload()
+		}
+	}
+}
+
+func (l *certificateLoader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, _ := l.current.Load().(*tls.Certificate)
+	if cert == nil {
+		return nil, errNoCertificate
+	}
+	return cert, nil
+}
+
+func (l *certificateLoader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: l.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+	}
+}
+
+func expvarString(s string) *expvar.String {
+	v := new(expvar.String)
+	v.Set(s)
+	return v
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	maxRoomNameLength   = 64
+	maxChatTextLength   = 4096
+	maxMembersPerRoom   = 1000
+	overflowDrop        = "drop"
+	overflowDisconnect  = "disconnect"
+	defaultChatBuffer   = 64
+	defaultChatOverflow = overflowDrop
+)
+
+var (
+	errRoomFull    = errors.New("room is full")
+	errSlowChatter = errors.New("subscriber is too slow to keep up with the room")
+)
+
+// chatHub fans the events of every room out to its members. Delivery never
+// blocks the sender: each member has a bounded buffer, and when it is full
+// the event is either dropped for that member or the member is disconnected.
+type chatHub struct {
+	bufferSize int
+	overflow   string
+
+	mu    sync.Mutex
+	rooms map[string]*chatRoom
+}
+
+type chatRoom struct {
+	name     string
+	sequence int64
+	members  map[*chatMember]struct{}
+}
+
+type chatMember struct {
+	dropped int64 // events dropped since the last DROPPED notice, first for 64-bit atomic alignment
+
+	name       string
+	events     chan *greetpb.ChatEvent
+	kicked     chan struct{}
+	kickedOnce sync.Once
+}
+
+func newChatHub(bufferSize int, overflow string) (*chatHub, error) {
+	if bufferSize < 1 {
+		return nil, fmt.Errorf("chat buffer size must be positive, got %d", bufferSize)
+	}
+	if overflow != overflowDrop && overflow != overflowDisconnect {
+		return nil, fmt.Errorf("chat overflow policy must be %q or %q, got %q", overflowDrop, overflowDisconnect, overflow)
+	}
+	return &chatHub{
+		bufferSize: bufferSize,
+		overflow:   overflow,
+		rooms:      make(map[string]*chatRoom),
+	}, nil
+}
+
+// join adds a member named name to room and announces it to everyone, the new member included.
+func (h *chatHub) join(room, name string) (*chatMember, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[room]
+	if !ok {
+		r = &chatRoom{name: room, members: make(map[*chatMember]struct{})}
+		h.rooms[room] = r
+	}
+	if len(r.members) >= maxMembersPerRoom {
+		return nil, errRoomFull
+	}
+	m := &chatMember{
+		name:   name,
+		events: make(chan *greetpb.ChatEvent, h.bufferSize),
+		kicked: make(chan struct{}),
+	}
+	r.members[m] = struct{}{}
+	h.broadcastLocked(r, &greetpb.ChatEvent{Type: greetpb.ChatEvent_JOINED, Sender: name})
+	return m, nil
+}
+
+// leave removes m from room and announces it to the remaining members.
+func (h *chatHub) leave(room string, m *chatMember) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[room]
+	if !ok {
+		return
+	}
+	delete(r.members, m)
+	if len(r.members) == 0 {
+		delete(h.rooms, room)
+		return
+	}
+	h.broadcastLocked(r, &greetpb.ChatEvent{Type: greetpb.ChatEvent_LEFT, Sender: m.name})
+}
+
+// send broadcasts text to room. It is ignored once from has left, because the
+// receiver of a stream can still be running after the stream left the room.
+func (h *chatHub) send(room string, from *chatMember, text string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.rooms[room]
+	if !ok {
+		return
+	}
+	if _, member := r.members[from]; member {
+		h.broadcastLocked(r, &greetpb.ChatEvent{Type: greetpb.ChatEvent_MESSAGE, Sender: from.name, Text: text})
+	}
+}
+
+// broadcastLocked must be called with h.mu held, which keeps the sequence
+// numbers in the same order as the events in every member's buffer.
+func (h *chatHub) broadcastLocked(r *chatRoom, event *greetpb.ChatEvent) {
+	r.sequence++
+	event.Room = r.name
+	event.Sequence = r.sequence
+	for m := range r.members {
+		select {
+		case m.events <- event:
+		default:
+			if h.overflow == overflowDisconnect {
+				m.kick()
+			} else {
+				atomic.AddInt64(&m.dropped, 1)
+			}
+		}
+	}
+}
+
+func (m *chatMember) kick() {
+	m.kickedOnce.Do(func() { close(m.kicked) })
+}
+
+// takeDropped returns the number of events dropped since the last call.
+func (m *chatMember) takeDropped() int64 {
+	return atomic.SwapInt64(&m.dropped, 0)
+}
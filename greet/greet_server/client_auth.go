@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"os"
+)
+
+// clientIdentity is the client certificate verified during an mTLS handshake.
+type clientIdentity struct {
+	CommonName string
+	DNSNames   []string
+	Emails     []string
+	URIs       []string
+}
+
+func (id *clientIdentity) String() string {
+	return fmt.Sprintf("CN=%v DNS=%v Email=%v URI=%v", id.CommonName, id.DNSNames, id.Emails, id.URIs)
+}
+
+// loadClientCAs reads the PEM bundle of the authorities allowed to sign client certificates.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
+
+// requireClientCertificates makes config reject clients without a certificate signed by one of cas.
+func requireClientCertificates(config *tls.Config, cas *x509.CertPool) {
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	config.ClientCAs = cas
+}
+
+// verifiedClient returns the identity of the leaf certificate of the verified chain of the peer.
+func verifiedClient(ctx context.Context) (*clientIdentity, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, status.Errorf(codes.Unauthenticated, "Unknown peer")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, status.Errorf(codes.Unauthenticated, "Missing verified client certificate")
+	}
+	leaf := info.State.VerifiedChains[0][0]
+	id := &clientIdentity{
+		CommonName: leaf.Subject.CommonName,
+		DNSNames:   leaf.DNSNames,
+		Emails:     leaf.EmailAddresses,
+	}
+	for _, uri := range leaf.URIs {
+		id.URIs = append(id.URIs, uri.String())
+	}
+	return id, nil
+}
+
+type clientIdentityKey struct{}
+
+// clientIdentityFromContext returns the verified client of an RPC, nil unless the server runs in mTLS mode.
+func clientIdentityFromContext(ctx context.Context) *clientIdentity {
+	id, _ := ctx.Value(clientIdentityKey{}).(*clientIdentity)
+	return id
+}
+
+func clientIdentityUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	id, err := verifiedClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, clientIdentityKey{}, id), req)
+}
+
+func clientIdentityStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	id, err := verifiedClient(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &identityStream{
+		ServerStream: stream,
+		ctx:          context.WithValue(stream.Context(), clientIdentityKey{}, id),
+	})
+}
+
+// identityStream overrides the context of a server stream so handlers can see the client.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+//go:embed catalog.json
+var defaultCatalog []byte
+
+// catalogFile is the JSON layout of a translation catalog, see catalog.json.
+type catalogFile struct {
+	DefaultLocale string `json:"default_locale"`
+	Locales       map[string]struct {
+		Greeting   string            `json:"greeting"`
+		Honorifics map[string]string `json:"honorifics"`
+	} `json:"locales"`
+}
+
+// catalog holds a greeting template per locale, keyed by lower case BCP 47 tag.
+type catalog struct {
+	defaultLocale string
+	translations  map[string]*translation
+}
+
+type translation struct {
+	greeting   *template.Template
+	honorifics map[string]string
+}
+
+// greetingData is what the templates of the catalog can use.
+type greetingData struct {
+	FirstName string
+	LastName  string
+	Honorific string // translated, empty when not given or unknown to the locale
+}
+
+var greetingFields = map[string]bool{"FirstName": true, "LastName": true, "Honorific": true}
+
+// loadCatalog reads the catalog at path, or the built-in one when path is empty.
+func loadCatalog(path string) (*catalog, error) {
+	data := defaultCatalog
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	var file catalogFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid catalog: %v", err)
+	}
+
+	c := &catalog{
+		defaultLocale: strings.ToLower(file.DefaultLocale),
+		translations:  make(map[string]*translation),
+	}
+	for locale, entry := range file.Locales {
+		tmpl, err := template.New(locale).Option("missingkey=error").Parse(entry.Greeting)
+		if err != nil {
+			return nil, fmt.Errorf("invalid greeting for %v: %v", locale, err)
+		}
+		// Catch templates using fields that do not exist now rather than on the
+		// first request, including in the branches an execution would skip
+		if err := checkTemplateNode(tmpl.Tree.Root, greetingFields); err != nil {
+			return nil, fmt.Errorf("invalid greeting for %v: %v", locale, err)
+		}
+		c.translations[strings.ToLower(locale)] = &translation{greeting: tmpl, honorifics: entry.Honorifics}
+	}
+	if _, ok := c.translations[c.defaultLocale]; !ok {
+		return nil, fmt.Errorf("default locale %q has no translation", file.DefaultLocale)
+	}
+	return c, nil
+}
+
+// resolve returns the translation for the first supported locale among the
+// greeting's, then the accept-language header's, then the default one.
+func (c *catalog) resolve(ctx context.Context, greeting *greetpb.Greeting) (string, *translation) {
+	candidates := []string{greeting.GetLocale()}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, header := range md.Get("accept-language") {
+			candidates = append(candidates, parseAcceptLanguage(header)...)
+		}
+	}
+	for _, candidate := range candidates {
+		// "fr-CA" falls back to "fr"
+		tag := strings.ToLower(strings.TrimSpace(candidate))
+		for tag != "" {
+			if t, ok := c.translations[tag]; ok {
+				return tag, t
+			}
+			i := strings.LastIndexAny(tag, "-_")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return c.defaultLocale, c.translations[c.defaultLocale]
+}
+
+// greet returns the localized "Hello <name>" for greeting.
+func (c *catalog) greet(ctx context.Context, greeting *greetpb.Greeting) (string, error) {
+	_, t := c.resolve(ctx, greeting)
+	data := greetingData{
+		FirstName: greeting.GetFirstName(),
+		LastName:  greeting.GetLastName(),
+		Honorific: t.honorifics[strings.ToLower(greeting.GetHonorific())],
+	}
+	var b strings.Builder
+	if err := t.greeting.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parseAcceptLanguage returns the tags of an accept-language header such as
+// "fr-CH, fr;q=0.9, en;q=0.8, *;q=0.5" by decreasing preference.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if tag == "" || tag == "*" || q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{tag, q})
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"google.golang.org/protobuf/proto"
+	"strings"
+)
+
+// Bounds of a LongGreet stream.
+const (
+	maxLongGreetMessages = 1000
+	maxLongGreetBytes    = 64 << 10
+)
+
+var (
+	errTooManyGreetings  = errors.New("too many greetings")
+	errGreetingsTooLarge = errors.New("greetings are too large")
+)
+
+type nameKey struct {
+	firstName, lastName string
+}
+
+// greetAggregator counts the names of a LongGreet stream, greeting each
+// distinct name once.
+type greetAggregator struct {
+	hello func(context.Context, *greetpb.Greeting) (string, error)
+
+	messages int
+	bytes    int
+	index    map[nameKey]*greetpb.GreetedName
+	greeted  []*greetpb.GreetedName
+	result   strings.Builder
+}
+
+func newGreetAggregator(hello func(context.Context, *greetpb.Greeting) (string, error)) *greetAggregator {
+	return &greetAggregator{hello: hello, index: make(map[nameKey]*greetpb.GreetedName)}
+}
+
+// add records a request, rejecting it when the stream exceeds its bounds.
+func (a *greetAggregator) add(ctx context.Context, req *greetpb.LongGreetRequest) (*greetpb.LongGreetAck, error) {
+	if a.messages+1 > maxLongGreetMessages {
+		return nil, errTooManyGreetings
+	}
+	size := proto.Size(req)
+	if a.bytes+size > maxLongGreetBytes {
+		return nil, errGreetingsTooLarge
+	}
+	a.messages++
+	a.bytes += size
+
+	greeting := req.GetGreeting()
+	key := nameKey{strings.TrimSpace(greeting.GetFirstName()), strings.TrimSpace(greeting.GetLastName())}
+	greeted, duplicate := a.index[key]
+	if !duplicate {
+		hello, err := a.hello(ctx, greeting)
+		if err != nil {
+			return nil, err
+		}
+		greeted = &greetpb.GreetedName{Greeting: greeting, Result: hello}
+		a.index[key] = greeted
+		a.greeted = append(a.greeted, greeted)
+	}
+	greeted.Count++
+	a.result.WriteString(greeted.GetResult() + "! ")
+
+	return &greetpb.LongGreetAck{
+		Sequence:  int32(a.messages),
+		Result:    greeted.GetResult(),
+		Duplicate: duplicate,
+		Count:     greeted.GetCount(),
+	}, nil
+}
+
+func (a *greetAggregator) response() *greetpb.LongGreetResponse {
+	return &greetpb.LongGreetResponse{
+		Result:   a.result.String(),
+		Greeted:  a.greeted,
+		Messages: int32(a.messages),
+	}
+}
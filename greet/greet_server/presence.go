@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"google.golang.org/grpc/peer"
+	"sort"
+	"sync"
+	"time"
+)
+
+const presenceWatcherBuffer = 256
+
+var errSlowWatcher = errors.New("watcher is too slow to keep up with presence changes")
+
+type presenceKey struct {
+	firstName, lastName, peerAddress string
+}
+
+type presenceEntry struct {
+	key     presenceKey
+	streams int
+	since   time.Time
+}
+
+func (e *presenceEntry) toProto() *greetpb.Presence {
+	return &greetpb.Presence{
+		Greeting:              &greetpb.Greeting{FirstName: e.key.firstName, LastName: e.key.lastName},
+		PeerAddress:           e.key.peerAddress,
+		Streams:               int32(e.streams),
+		OnlineSinceUnixMillis: e.since.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+// presenceTracker counts the open streams of every client and notifies the
+// watchers when a client comes online or goes offline.
+type presenceTracker struct {
+	mu       sync.Mutex
+	entries  map[presenceKey]*presenceEntry
+	watchers map[*presenceWatcher]struct{}
+}
+
+type presenceWatcher struct {
+	events     chan *greetpb.PresenceEvent
+	kicked     chan struct{}
+	kickedOnce sync.Once
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{
+		entries:  make(map[presenceKey]*presenceEntry),
+		watchers: make(map[*presenceWatcher]struct{}),
+	}
+}
+
+// track marks the client of the stream with ctx as online until the returned function is called.
+func (t *presenceTracker) track(ctx context.Context, greeting *greetpb.Greeting) (untrack func()) {
+	key := presenceKey{
+		firstName: greeting.GetFirstName(),
+		lastName:  greeting.GetLastName(),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		key.peerAddress = p.Addr.String()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &presenceEntry{key: key, since: time.Now()}
+		t.entries[key] = entry
+	}
+	entry.streams++
+	if entry.streams == 1 {
+		t.publishLocked(&greetpb.PresenceEvent{Type: greetpb.PresenceEvent_ONLINE, Presence: entry.toProto()})
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.release(entry) })
+	}
+}
+
+func (t *presenceTracker) release(entry *presenceEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry.streams--
+	if entry.streams == 0 {
+		delete(t.entries, entry.key)
+		t.publishLocked(&greetpb.PresenceEvent{Type: greetpb.PresenceEvent_OFFLINE, Presence: entry.toProto()})
+	}
+}
+
+// list returns the clients online, the longest connected first.
+func (t *presenceTracker) list() []*greetpb.Presence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.listLocked()
+}
+
+func (t *presenceTracker) listLocked() []*greetpb.Presence {
+	entries := make([]*presenceEntry, 0, len(t.entries))
+	for _, e := range t.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].since.Before(entries[j].since) })
+
+	presences := make([]*greetpb.Presence, len(entries))
+	for i, e := range entries {
+		presences[i] = e.toProto()
+	}
+	return presences
+}
+
+// watch registers a watcher and returns it with the clients online at that
+// moment, so that no change is missed or reported twice.
+func (t *presenceTracker) watch() (*presenceWatcher, []*greetpb.Presence) {
+	w := &presenceWatcher{
+		events: make(chan *greetpb.PresenceEvent, presenceWatcherBuffer),
+		kicked: make(chan struct{}),
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.watchers[w] = struct{}{}
+	return w, t.listLocked()
+}
+
+func (t *presenceTracker) unwatch(w *presenceWatcher) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.watchers, w)
+}
+
+// publishLocked must be called with t.mu held. Watchers whose buffer is full
+// are disconnected, a dropped presence event would leave them out of sync.
+func (t *presenceTracker) publishLocked(event *greetpb.PresenceEvent) {
+	for w := range t.watchers {
+		select {
+		case w.events <- event:
+		default:
+			w.kickedOnce.Do(func() { close(w.kicked) })
+		}
+	}
+}
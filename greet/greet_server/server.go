@@ -2,61 +2,162 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"io"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 )
 
 type server struct {
 	greetpb.UnimplementedGreetServiceServer
+	chat      *chatHub
+	presence  *presenceTracker
+	catalog   *catalog
+	templates *templateCache
 }
 
+const port = 50051
+
+// Transport security modes of the -tls flag.
 const (
-	port = 50051
-	tls  = true
+	tlsNone   = "none"
+	tlsServer = "tls"
+	tlsMutual = "mtls"
+)
+
+// Bounds of a GreetManyTimes stream.
+const (
+	defaultGreetCount    = 10
+	maxGreetCount        = 1000
+	defaultGreetInterval = time.Second
+	minGreetInterval     = 10 * time.Millisecond
+	maxGreetInterval     = time.Minute
+)
+
+// Bounds of the simulated work of GreetWithDeadline.
+const (
+	defaultDeadlineWork = 3 * time.Second
+	maxDeadlineWork     = time.Minute
+)
+
+var (
+	chatBufferSize = flag.Int("chat-buffer-size", defaultChatBuffer, "Events buffered for each Chat subscriber")
+	chatOverflow   = flag.String("chat-overflow", defaultChatOverflow, "What happens to a Chat subscriber whose buffer is full: drop or disconnect")
+
+	catalogPath = flag.String("catalog", "", "Translation catalog, see catalog.json for the format and the built-in default")
+
+	templatesPath = flag.String("templates", "", "JSON file keeping the greeting templates, they are kept in memory when empty")
+	adminToken    = flag.String("admin-token", "", "Bearer token required to change greeting templates, they are read-only when empty")
+
+	tlsMode          = flag.String("tls", tlsServer, "Transport security: none, tls, or mtls to also require client certificates, health and reflection included, see -health-addr")
+	healthAddr       = flag.String("health-addr", "", "Address serving only the health service without requiring client certificates, for probes in mtls mode, empty to disable")
+	clientCAPath     = flag.String("client-ca", "ssl/ca.crt", "PEM bundle of the authorities signing client certificates in mtls mode")
+	certPollInterval = flag.Duration("cert-poll-interval", 10*time.Second, "How often to check the TLS certificate files for changes, 0 to only reload on SIGHUP")
+	debugAddr        = flag.String("debug-addr", "", "Address serving /debug/vars with the active certificate, empty to disable")
+
+	keepaliveTime    = flag.Duration("keepalive-time", 30*time.Second, "Ping clients after this long without activity")
+	keepaliveTimeout = flag.Duration("keepalive-timeout", 10*time.Second, "Close connections whose ping is not answered within this time")
 )
 
-func (*server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
+func (s *server) Greet(ctx context.Context, req *greetpb.GreetRequest) (*greetpb.GreetResponse, error) {
 	fmt.Printf("Greet function was invoked with %v\n", req)
-	firstName := req.GetGreeting().GetFirstName()
-	result := "Hello " + firstName
+	if client := clientIdentityFromContext(ctx); client != nil {
+		fmt.Printf("Greet was called by client %v\n", client)
+	}
+	var result string
+	var err error
+	if name := req.GetTemplate(); name != "" {
+		result, err = s.templates.greet(ctx, name, req.GetGreeting())
+		if errors.Is(err, errTemplateNotFound) {
+			return nil, status.Errorf(codes.NotFound, "Cannot find template %q", name)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Cannot render template %q: %v", name, err)
+		}
+	} else if result, err = s.hello(ctx, req.GetGreeting()); err != nil {
+		return nil, err
+	}
 	res := &greetpb.GreetResponse{
 		Result: result,
 	}
 	return res, nil
 }
 
-func (*server) GreetManyTimes(
+func (s *server) GreetManyTimes(
 	req *greetpb.GreetManyTimesRequest,
 	stream greetpb.GreetService_GreetManyTimesServer,
 ) error {
-	firstName := req.GetGreeting().GetFirstName()
-	for i := 0; i < 10; i++ {
-		result := "Hello " + firstName + " number " + strconv.Itoa(i)
+	fmt.Printf("GreetManyTimes function was invoked with %v\n", req)
+	count := int(req.GetCount())
+	if count == 0 {
+		count = defaultGreetCount
+	}
+	if count < 0 || count > maxGreetCount {
+		return status.Errorf(codes.InvalidArgument, "Count must be between 1 and %d", maxGreetCount)
+	}
+	// Check the millis before converting, a large value overflows the Duration
+	interval := defaultGreetInterval
+	if millis := req.GetIntervalMillis(); millis != 0 {
+		if millis < minGreetInterval.Milliseconds() || millis > maxGreetInterval.Milliseconds() {
+			return status.Errorf(codes.InvalidArgument, "Interval must be between %v and %v", minGreetInterval, maxGreetInterval)
+		}
+		interval = time.Duration(millis) * time.Millisecond
+	}
+
+	defer s.presence.track(stream.Context(), req.GetGreeting())()
+	hello, err := s.hello(stream.Context(), req.GetGreeting())
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ticker.C:
+			case <-stream.Context().Done():
+				// The client went away, stop right now rather than at the next tick
+				fmt.Println("The client canceled GreetManyTimes!")
+				return status.FromContextError(stream.Context().Err()).Err()
+			}
+		}
+		result := hello + " number " + strconv.Itoa(i)
 		res := &greetpb.GreetManyTimesResponse{
-			Result: result,
+			Result:           result,
+			Sequence:         int32(i + 1),
+			SentAtUnixMillis: time.Now().UnixNano() / int64(time.Millisecond),
 		}
 		err := stream.Send(res)
 		if err != nil {
 			log.Printf("Erorr while sending data to client: %v\n", err)
 			return err
 		}
-		time.Sleep(1000 * time.Millisecond)
 	}
 	return nil
 }
 
-func (*server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
+func (s *server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
 	fmt.Println("LongGreet function was invoked with a streaming request")
-	result := ""
+	greetings := newGreetAggregator(s.hello)
 
 	for {
 		req, err := stream.Recv()
@@ -67,22 +168,65 @@ func (*server) LongGreet(stream greetpb.GreetService_LongGreetServer) error {
 			log.Printf("Erorr while reading client stream: %v", err)
 			return err
 		}
-		firstName := req.GetGreeting().GetFirstName()
-		result += "Hello " + firstName + "! "
+		if _, err := greetings.add(stream.Context(), req); err != nil {
+			return longGreetStatus(err)
+		}
 	}
-	err := stream.SendAndClose(&greetpb.LongGreetResponse{
-		Result: result,
-	})
+	err := stream.SendAndClose(greetings.response())
 	if err != nil {
 		log.Printf("Erorr while sending data to client: %v\n", err)
 	}
 	return err
 }
 
-func (*server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
-	fmt.Println("GreetEveryone function was invoked with a streaming request")
+func (s *server) LongGreetWithAcks(stream greetpb.GreetService_LongGreetWithAcksServer) error {
+	fmt.Println("LongGreetWithAcks function was invoked with a streaming request")
+	greetings := newGreetAggregator(s.hello)
 
 	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break // Finish reading the client stream
+		}
+		if err != nil {
+			log.Printf("Erorr while reading client stream: %v", err)
+			return err
+		}
+		ack, err := greetings.add(stream.Context(), req)
+		if err != nil {
+			return longGreetStatus(err)
+		}
+		err = stream.Send(&greetpb.LongGreetEvent{Event: &greetpb.LongGreetEvent_Ack{Ack: ack}})
+		if err != nil {
+			log.Printf("Erorr while sending data to client: %v\n", err)
+			return err
+		}
+	}
+	err := stream.Send(&greetpb.LongGreetEvent{Event: &greetpb.LongGreetEvent_Summary{Summary: greetings.response()}})
+	if err != nil {
+		log.Printf("Erorr while sending data to client: %v\n", err)
+	}
+	return err
+}
+
+// longGreetStatus maps exceeded bounds to RESOURCE_EXHAUSTED, other errors already are statuses.
+func longGreetStatus(err error) error {
+	if errors.Is(err, errTooManyGreetings) || errors.Is(err, errGreetingsTooLarge) {
+		return status.Errorf(
+			codes.ResourceExhausted,
+			fmt.Sprintf("Stream rejected: %v, the limits are %d messages and %d bytes", err, maxLongGreetMessages, maxLongGreetBytes),
+		)
+	}
+	return err
+}
+
+func (s *server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) error {
+	fmt.Println("GreetEveryone function was invoked with a streaming request")
+
+	// The client is present under the greeting of its first message
+	untrack := func() {}
+	defer func() { untrack() }()
+	for first := true; ; first = false {
 		req, err := stream.Recv()
 		if err == io.EOF {
 			return nil
@@ -91,8 +235,14 @@ func (*server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) er
 			log.Printf("Erorr while reading client stream: %v\n", err)
 			return err
 		}
-		firstName := req.GetGreeting().GetFirstName()
-		result := "Hello " + firstName + "! "
+		if first {
+			untrack = s.presence.track(stream.Context(), req.GetGreeting())
+		}
+		hello, err := s.hello(stream.Context(), req.GetGreeting())
+		if err != nil {
+			return err
+		}
+		result := hello + "! "
 		sendErr := stream.Send(&greetpb.GreetEveryoneResponse{
 			Result: result,
 		})
@@ -103,50 +253,333 @@ func (*server) GreetEveryone(stream greetpb.GreetService_GreetEveryoneServer) er
 	}
 }
 
-func (*server) GreetWithDeadline(
+func (s *server) Chat(stream greetpb.GreetService_ChatServer) error {
+	fmt.Println("Chat function was invoked with a streaming request")
+
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Erorr while reading client stream: %v\n", err)
+		return err
+	}
+	room := req.GetRoom()
+	name := req.GetGreeting().GetFirstName()
+	if room == "" || len(room) > maxRoomNameLength {
+		return status.Errorf(codes.InvalidArgument, "The first message must name a room of at most %d characters", maxRoomNameLength)
+	}
+	if name == "" {
+		return status.Error(codes.InvalidArgument, "The first message must have a first name")
+	}
+
+	member, err := s.chat.join(room, name)
+	if err != nil {
+		return status.Errorf(codes.ResourceExhausted, "Cannot join %v: %v", room, err)
+	}
+	defer s.chat.leave(room, member)
+	defer s.presence.track(stream.Context(), req.GetGreeting())()
+	if err := s.sendChatText(room, member, req.GetText()); err != nil {
+		return err
+	}
+
+	// Receive in the background so that events keep flowing while the client is quiet
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			if err := s.sendChatText(room, member, req.GetText()); err != nil {
+				recvErr <- err
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-member.events:
+			if err := stream.Send(event); err != nil {
+				log.Printf("Erorr while sending data to client: %v\n", err)
+				return err
+			}
+			if dropped := member.takeDropped(); dropped > 0 {
+				err := stream.Send(&greetpb.ChatEvent{Type: greetpb.ChatEvent_DROPPED, Room: room, Dropped: dropped})
+				if err != nil {
+					log.Printf("Erorr while sending data to client: %v\n", err)
+					return err
+				}
+			}
+		case <-member.kicked:
+			return status.Errorf(codes.ResourceExhausted, "Disconnected from %v: %v", room, errSlowChatter)
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
+			if _, ok := status.FromError(err); !ok {
+				log.Printf("Erorr while reading client stream: %v\n", err)
+			}
+			return err
+		}
+	}
+}
+
+// sendChatText broadcasts a non-empty text to the room.
+func (s *server) sendChatText(room string, member *chatMember, text string) error {
+	if text == "" {
+		return nil
+	}
+	if len(text) > maxChatTextLength {
+		return status.Errorf(codes.InvalidArgument, "Messages are limited to %d bytes", maxChatTextLength)
+	}
+	s.chat.send(room, member, text)
+	return nil
+}
+
+func (s *server) ListPresence(
+	ctx context.Context,
+	req *greetpb.ListPresenceRequest,
+) (*greetpb.ListPresenceResponse, error) {
+	fmt.Printf("ListPresence function was invoked with %v\n", req)
+	return &greetpb.ListPresenceResponse{Presences: s.presence.list()}, nil
+}
+
+func (s *server) WatchPresence(req *greetpb.WatchPresenceRequest, stream greetpb.GreetService_WatchPresenceServer) error {
+	fmt.Printf("WatchPresence function was invoked with %v\n", req)
+	watcher, online := s.presence.watch()
+	defer s.presence.unwatch(watcher)
+
+	for _, presence := range online {
+		err := stream.Send(&greetpb.PresenceEvent{
+			Type:     greetpb.PresenceEvent_ONLINE,
+			Presence: presence,
+			Snapshot: true,
+		})
+		if err != nil {
+			log.Printf("Erorr while sending data to client: %v\n", err)
+			return err
+		}
+	}
+
+	for {
+		select {
+		case event := <-watcher.events:
+			if err := stream.Send(event); err != nil {
+				log.Printf("Erorr while sending data to client: %v\n", err)
+				return err
+			}
+		case <-watcher.kicked:
+			return status.Error(codes.ResourceExhausted, errSlowWatcher.Error())
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+func (s *server) GreetWithDeadline(
 	ctx context.Context,
 	req *greetpb.GreetWithDeadlineRequest,
 ) (*greetpb.GreetWithDeadlineResponse, error) {
 	fmt.Printf("GreetWithDeadline function was invoked with %v\n", req)
-	for i := 0; i < 3; i++ {
-		if ctx.Err() == context.Canceled {
-			//the client canceled the request
-			fmt.Println("The client canceled the request!")
-			return nil, status.Error(codes.Canceled, "The client canceled the request")
+	// Check the millis before converting, a large value overflows the Duration
+	work := defaultDeadlineWork
+	if millis := req.GetWorkMillis(); millis != 0 {
+		if millis < 0 || millis > maxDeadlineWork.Milliseconds() {
+			return nil, status.Errorf(codes.InvalidArgument, "Work must be between 0 and %v", maxDeadlineWork)
+		}
+		work = time.Duration(millis) * time.Millisecond
+	}
+
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		budget := time.Until(deadline)
+		defer func() {
+			// Trailers are sent with the status, whether the call succeeded or not
+			err := grpc.SetTrailer(ctx, metadata.Pairs(
+				"deadline-budget-ms", strconv.FormatInt(budget.Milliseconds(), 10),
+				"deadline-remaining-ms", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10),
+			))
+			if err != nil {
+				log.Printf("Erorr while setting trailers: %v\n", err)
+			}
+		}()
+	}
+
+	start := time.Now()
+	timer := time.NewTimer(work)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		done := time.Since(start).Round(time.Millisecond)
+		if ctx.Err() == context.DeadlineExceeded {
+			fmt.Println("The deadline of the request expired!")
+			return nil, status.Errorf(codes.DeadlineExceeded, "The deadline expired after %v of %v of work", done, work)
 		}
-		time.Sleep(1 * time.Second)
+		//the client canceled the request
+		fmt.Println("The client canceled the request!")
+		return nil, status.Errorf(codes.Canceled, "The client canceled the request after %v of %v of work", done, work)
 	}
 
-	firstName := req.GetGreeting().GetFirstName()
-	result := "Hello " + firstName
+	result, err := s.hello(ctx, req.GetGreeting())
+	if err != nil {
+		return nil, err
+	}
 	res := &greetpb.GreetWithDeadlineResponse{
 		Result: result,
 	}
 	return res, nil
 }
 
+// hello returns the greeting translated for the locale of the request.
+func (s *server) hello(ctx context.Context, greeting *greetpb.Greeting) (string, error) {
+	result, err := s.catalog.greet(ctx, greeting)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "Cannot translate the greeting: %v", err)
+	}
+	return result, nil
+}
+
 func main() {
 	fmt.Println("Hello world!")
+	flag.Parse()
+
+	chat, err := newChatHub(*chatBufferSize, *chatOverflow)
+	if err != nil {
+		log.Fatalf("Invalid chat configuration: %v", err)
+	}
+	greetings, err := loadCatalog(*catalogPath)
+	if err != nil {
+		log.Fatalf("Failed loading the translation catalog: %v", err)
+	}
+	var templates templateStore = newMemoryTemplateStore()
+	if *templatesPath != "" {
+		if templates, err = newFileTemplateStore(*templatesPath); err != nil {
+			log.Fatalf("Failed loading the greeting templates: %v", err)
+		}
+	}
+	if *adminToken == "" {
+		log.Println("No admin token, the greeting templates are read-only")
+	}
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
 
-	opts := []grpc.ServerOption{}
-	if tls {
+	opts := []grpc.ServerOption{
+		// Ping idle clients so that dead peers are disconnected and go offline
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    *keepaliveTime,
+			Timeout: *keepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             10 * time.Second,
+			PermitWithoutStream: true,
+		}),
+	}
+
+	// Every service is reported, "" standing for the server as a whole
+	healthServer := health.NewServer()
+	setServingStatus := func(serving healthpb.HealthCheckResponse_ServingStatus) {
+		for _, service := range []string{"", "greet.GreetService", "greet.GreetingTemplateService"} {
+			healthServer.SetServingStatus(service, serving)
+		}
+	}
+	setServingStatus(healthpb.HealthCheckResponse_SERVING)
+
+	if *debugAddr != "" {
+		go func() {
+			// expvar registers /debug/vars on the default mux
+			if err := http.ListenAndServe(*debugAddr, nil); err != nil {
+				log.Printf("Debug server stopped: %v\n", err)
+			}
+		}()
+	}
+
+	// Options of the health-only server, which uses TLS without client certificates
+	var healthOpts []grpc.ServerOption
+	switch *tlsMode {
+	case tlsNone:
+		log.Println("TLS is disabled, connections are not encrypted")
+	case tlsServer, tlsMutual:
 		certFile := "ssl/server.crt"
 		keyFile := "ssl/server.pem"
-		creds, sslErr := credentials.NewServerTLSFromFile(certFile, keyFile)
-		if sslErr != nil {
-			log.Fatalf("Failed loading certificates: %v", sslErr)
+		// A failed reload keeps serving the previous pair but reports NOT_SERVING until the files are fixed
+		certs := newCertificateLoader(certFile, keyFile, func(err error) {
+			if err != nil {
+				setServingStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+				return
+			}
+			setServingStatus(healthpb.HealthCheckResponse_SERVING)
+		})
+		// Without a first pair there is nothing to serve
+		if err := certs.reload(); err != nil {
+			log.Fatalf("Failed loading certificates: %v", err)
+		}
+
+		// Reload the certificates when they are replaced, or on SIGHUP
+		if *certPollInterval > 0 {
+			go certs.watch(*certPollInterval)
 		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				certs.reload()
+			}
+		}()
 
-		opts = append(opts, grpc.Creds(creds))
+		healthOpts = append(healthOpts, grpc.Creds(credentials.NewTLS(certs.tlsConfig())))
+		config := certs.tlsConfig()
+		if *tlsMode == tlsMutual {
+			cas, err := loadClientCAs(*clientCAPath)
+			if err != nil {
+				log.Fatalf("Failed loading the client certificate authorities: %v", err)
+			}
+			requireClientCertificates(config, cas)
+			opts = append(opts,
+				grpc.ChainUnaryInterceptor(clientIdentityUnaryInterceptor),
+				grpc.ChainStreamInterceptor(clientIdentityStreamInterceptor),
+			)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(config)))
+	default:
+		log.Fatalf("Invalid -tls mode %q, use none, tls or mtls", *tlsMode)
 	}
 
 	s := grpc.NewServer(opts...)
-	greetpb.RegisterGreetServiceServer(s, &server{})
+	greetpb.RegisterGreetServiceServer(s, &server{
+		chat:      chat,
+		presence:  newPresenceTracker(),
+		catalog:   greetings,
+		templates: newTemplateCache(templates),
+	})
+	greetpb.RegisterGreetingTemplateServiceServer(s, &templateServer{
+		store:      templates,
+		adminToken: *adminToken,
+	})
+	healthpb.RegisterHealthServer(s, healthServer)
+
+	// Register reflection service on gRPC server.
+	reflection.Register(s)
+
+	if *healthAddr != "" {
+		healthLis, err := net.Listen("tcp", *healthAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen for health checks: %v", err)
+		}
+		healthOnly := grpc.NewServer(healthOpts...)
+		healthpb.RegisterHealthServer(healthOnly, healthServer)
+		go func() {
+			if err := healthOnly.Serve(healthLis); err != nil {
+				log.Printf("Health server stopped: %v\n", err)
+			}
+		}()
+	}
 
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	errTemplateNotFound = errors.New("template not found")
+	errTemplateExists   = errors.New("template already exists")
+)
+
+type greetingTemplate struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+
+	// Version changes every time the template is saved, it is not persisted.
+	Version uint64 `json:"-"`
+}
+
+// templateStore persists greeting templates. Implementations return
+// errTemplateNotFound and errTemplateExists for missing and duplicate names.
+type templateStore interface {
+	Create(ctx context.Context, t *greetingTemplate) error
+	Get(ctx context.Context, name string) (*greetingTemplate, error)
+	Update(ctx context.Context, t *greetingTemplate) error
+	Delete(ctx context.Context, name string) error
+	List(ctx context.Context) ([]*greetingTemplate, error)
+}
+
+// memoryTemplateStore keeps templates in memory, they are lost on restart.
+type memoryTemplateStore struct {
+	mu        sync.RWMutex
+	templates map[string]greetingTemplate
+	version   uint64 // last version given to a template
+}
+
+func newMemoryTemplateStore() *memoryTemplateStore {
+	return &memoryTemplateStore{templates: make(map[string]greetingTemplate)}
+}
+
+func (s *memoryTemplateStore) Create(_ context.Context, t *greetingTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[t.Name]; ok {
+		return errTemplateExists
+	}
+	t.Version = s.nextVersionLocked()
+	s.templates[t.Name] = *t
+	return nil
+}
+
+func (s *memoryTemplateStore) Get(_ context.Context, name string) (*greetingTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.templates[name]
+	if !ok {
+		return nil, errTemplateNotFound
+	}
+	return &t, nil
+}
+
+func (s *memoryTemplateStore) Update(_ context.Context, t *greetingTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[t.Name]; !ok {
+		return errTemplateNotFound
+	}
+	t.Version = s.nextVersionLocked()
+	s.templates[t.Name] = *t
+	return nil
+}
+
+func (s *memoryTemplateStore) Delete(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.templates[name]; !ok {
+		return errTemplateNotFound
+	}
+	delete(s.templates, name)
+	return nil
+}
+
+func (s *memoryTemplateStore) nextVersionLocked() uint64 {
+	s.version++
+	return s.version
+}
+
+// List returns the templates sorted by name.
+func (s *memoryTemplateStore) List(_ context.Context) ([]*greetingTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.listLocked(), nil
+}
+
+func (s *memoryTemplateStore) listLocked() []*greetingTemplate {
+	list := make([]*greetingTemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		t := t
+		list = append(list, &t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// fileTemplateStore keeps templates in memory and rewrites a JSON file after
+// every change, so they survive restarts. A change that cannot be saved is
+// rolled back.
+type fileTemplateStore struct {
+	path   string
+	memory *memoryTemplateStore
+}
+
+// newFileTemplateStore loads the templates of path, which may not exist yet.
+// The file may have been edited by hand, so its templates are validated again.
+func newFileTemplateStore(path string) (*fileTemplateStore, error) {
+	s := &fileTemplateStore{path: path, memory: newMemoryTemplateStore()}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var templates []*greetingTemplate
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if _, err := parseGreetingTemplate(t); err != nil {
+			return nil, fmt.Errorf("invalid template %q in %s: %v", t.Name, path, err)
+		}
+		if _, ok := s.memory.templates[t.Name]; ok {
+			return nil, fmt.Errorf("duplicate template %q in %s", t.Name, path)
+		}
+		t.Version = s.memory.nextVersionLocked()
+		s.memory.templates[t.Name] = *t
+	}
+	return s, nil
+}
+
+func (s *fileTemplateStore) Create(ctx context.Context, t *greetingTemplate) error {
+	return s.change(func(m map[string]greetingTemplate) error {
+		if _, ok := m[t.Name]; ok {
+			return errTemplateExists
+		}
+		t.Version = s.memory.nextVersionLocked()
+		m[t.Name] = *t
+		return nil
+	})
+}
+
+func (s *fileTemplateStore) Get(ctx context.Context, name string) (*greetingTemplate, error) {
+	return s.memory.Get(ctx, name)
+}
+
+func (s *fileTemplateStore) Update(ctx context.Context, t *greetingTemplate) error {
+	return s.change(func(m map[string]greetingTemplate) error {
+		if _, ok := m[t.Name]; !ok {
+			return errTemplateNotFound
+		}
+		t.Version = s.memory.nextVersionLocked()
+		m[t.Name] = *t
+		return nil
+	})
+}
+
+func (s *fileTemplateStore) Delete(ctx context.Context, name string) error {
+	return s.change(func(m map[string]greetingTemplate) error {
+		if _, ok := m[name]; !ok {
+			return errTemplateNotFound
+		}
+		delete(m, name)
+		return nil
+	})
+}
+
+func (s *fileTemplateStore) List(ctx context.Context) ([]*greetingTemplate, error) {
+	return s.memory.List(ctx)
+}
+
+// change applies fn to a copy of the templates and keeps the result once it is on disk.
+func (s *fileTemplateStore) change(fn func(map[string]greetingTemplate) error) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+
+	previous := s.memory.templates
+	next := make(map[string]greetingTemplate, len(previous)+1)
+	for name, t := range previous {
+		next[name] = t
+	}
+	if err := fn(next); err != nil {
+		return err
+	}
+	s.memory.templates = next
+	if err := s.saveLocked(); err != nil {
+		s.memory.templates = previous
+		return err
+	}
+	return nil
+}
+
+// saveLocked writes the file atomically so that a crash never leaves it half written.
+func (s *fileTemplateStore) saveLocked() error {
+	data, err := json.MarshalIndent(s.memory.listLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Bounds of a greeting template and of what it renders.
+const (
+	maxTemplateBodyLength = 4 << 10
+	maxTemplateOutput     = 4 << 10
+	maxTemplateRender     = 100 * time.Millisecond
+)
+
+var (
+	templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+	errTemplateOutputTooLarge = errors.New("template output is too large")
+)
+
+// templateFuncs are the only functions a greeting template may call, none of
+// them can loop.
+var templateFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// templateData exposes the Greeting fields to the templates.
+type templateData struct {
+	FirstName string
+	LastName  string
+	Locale    string
+	Honorific string
+}
+
+var templateFields = map[string]bool{"FirstName": true, "LastName": true, "Locale": true, "Honorific": true}
+
+// sampleTemplateData is used to render a template once before it is saved.
+var sampleTemplateData = templateData{FirstName: "Stephane", LastName: "Marek", Locale: "en", Honorific: "dr"}
+
+// parseGreetingTemplate checks the name and body of t and renders it once, so
+// that templates using unknown fields or producing huge outputs are rejected on save.
+func parseGreetingTemplate(t *greetingTemplate) (*template.Template, error) {
+	if !templateNamePattern.MatchString(t.Name) {
+		return nil, fmt.Errorf("invalid name %q, use lower case letters, digits, '-' and '_'", t.Name)
+	}
+	if t.Body == "" || len(t.Body) > maxTemplateBodyLength {
+		return nil, fmt.Errorf("body must have between 1 and %d bytes", maxTemplateBodyLength)
+	}
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("define and block are not allowed")
+	}
+	if err := checkTemplateNode(tmpl.Tree.Root, templateFields); err != nil {
+		return nil, err
+	}
+	if _, err := renderTemplate(context.Background(), tmpl, sampleTemplateData); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// checkTemplateNode allows text, the given fields, conditionals and the
+// templateFuncs, so that rendering time only grows with the length of the body.
+func checkTemplateNode(node parse.Node, fields map[string]bool) error {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		// Checked here as well as by rendering, which skips the branches not taken
+		if len(n.Ident) != 1 || !fields[n.Ident[0]] {
+			return fmt.Errorf("unknown field %v", n)
+		}
+		return nil
+	case nil, *parse.TextNode, *parse.CommentNode, *parse.DotNode,
+		*parse.StringNode, *parse.NumberNode, *parse.BoolNode, *parse.NilNode:
+		return nil
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkTemplateNode(child, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.ActionNode:
+		return checkTemplateNode(n.Pipe, fields)
+	case *parse.IfNode:
+		return checkTemplateBranch(&n.BranchNode, fields)
+	case *parse.WithNode:
+		return checkTemplateBranch(&n.BranchNode, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		if len(n.Decl) > 0 {
+			return errors.New("variables are not allowed")
+		}
+		for _, cmd := range n.Cmds {
+			if err := checkTemplateNode(cmd, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := checkTemplateNode(arg, fields); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *parse.IdentifierNode:
+		if !templateFuncs[n.Ident] {
+			return fmt.Errorf("function %q is not allowed", n.Ident)
+		}
+		return nil
+	}
+	return fmt.Errorf("%v is not allowed, use fields, if and with", node)
+}
+
+func checkTemplateBranch(n *parse.BranchNode, fields map[string]bool) error {
+	for _, child := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := checkTemplateNode(child, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderTemplate executes tmpl for at most maxTemplateRender, or until ctx is done.
+func renderTemplate(ctx context.Context, tmpl *template.Template, data templateData) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, maxTemplateRender)
+	defer cancel()
+
+	w := &limitedBuilder{ctx: ctx, limit: maxTemplateOutput}
+	done := make(chan error, 1)
+	go func() {
+		done <- tmpl.Execute(w, data)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", err
+		}
+		return w.String(), nil
+	case <-ctx.Done():
+		// The execution stops at its next write
+		return "", ctx.Err()
+	}
+}
+
+// limitedBuilder fails writes past limit, which stops templates that expand
+// exponentially through nested template calls, and writes once ctx is done.
+type limitedBuilder struct {
+	strings.Builder
+	ctx   context.Context
+	limit int
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if b.Len()+len(p) > b.limit {
+		return 0, errTemplateOutputTooLarge
+	}
+	return b.Builder.Write(p)
+}
+
+// templateCache keeps the parsed templates of a store, parsing them again
+// only once they are saved with a new version.
+type templateCache struct {
+	store templateStore
+
+	mu     sync.Mutex
+	parsed map[string]*cachedTemplate
+}
+
+type cachedTemplate struct {
+	version uint64
+	tmpl    *template.Template
+}
+
+func newTemplateCache(store templateStore) *templateCache {
+	return &templateCache{store: store, parsed: make(map[string]*cachedTemplate)}
+}
+
+func (c *templateCache) get(ctx context.Context, name string) (*template.Template, error) {
+	t, err := c.store.Get(ctx, name)
+	if err != nil {
+		c.mu.Lock()
+		delete(c.parsed, name)
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.mu.Lock()
+	cached, ok := c.parsed[name]
+	c.mu.Unlock()
+	if ok && cached.version == t.Version {
+		return cached.tmpl, nil
+	}
+
+	// Parsing includes a test render, so it runs without holding the lock
+	tmpl, err := parseGreetingTemplate(t)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Keep whichever of two concurrent parses saw the newer version
+	if cached, ok := c.parsed[name]; !ok || cached.version < t.Version {
+		c.parsed[name] = &cachedTemplate{version: t.Version, tmpl: tmpl}
+	}
+	return tmpl, nil
+}
+
+// greet renders the template named name for greeting.
+func (c *templateCache) greet(ctx context.Context, name string, greeting *greetpb.Greeting) (string, error) {
+	tmpl, err := c.get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(ctx, tmpl, templateData{
+		FirstName: greeting.GetFirstName(),
+		LastName:  greeting.GetLastName(),
+		Locale:    greeting.GetLocale(),
+		Honorific: greeting.GetHonorific(),
+	})
+}
+
+// templateServer implements GreetingTemplateService.
+type templateServer struct {
+	greetpb.UnimplementedGreetingTemplateServiceServer
+	store      templateStore
+	adminToken string // mutations are refused when empty
+}
+
+// requireAdmin checks the bearer token of a mutation.
+func (s *templateServer) requireAdmin(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Error(codes.FailedPrecondition, "Templates are read-only, start greet_server with -admin-token to change them")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, "Missing authorization header")
+	}
+	if !strings.HasPrefix(values[0], "Bearer ") {
+		return status.Error(codes.Unauthenticated, "Authorization header must use the Bearer scheme")
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+		return status.Error(codes.PermissionDenied, "Only admins can change templates")
+	}
+	return nil
+}
+
+func (s *templateServer) CreateTemplate(
+	ctx context.Context,
+	req *greetpb.CreateTemplateRequest,
+) (*greetpb.CreateTemplateResponse, error) {
+	fmt.Printf("CreateTemplate function was invoked with %v\n", req)
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	t := templateFromProto(req.GetTemplate())
+	if _, err := parseGreetingTemplate(t); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid template: %v", err)
+	}
+	if err := s.store.Create(ctx, t); err != nil {
+		return nil, templateStatus(err)
+	}
+	return &greetpb.CreateTemplateResponse{Template: templateToProto(t)}, nil
+}
+
+func (s *templateServer) ReadTemplate(
+	ctx context.Context,
+	req *greetpb.ReadTemplateRequest,
+) (*greetpb.ReadTemplateResponse, error) {
+	fmt.Printf("ReadTemplate function was invoked with %v\n", req)
+	t, err := s.store.Get(ctx, req.GetName())
+	if err != nil {
+		return nil, templateStatus(err)
+	}
+	return &greetpb.ReadTemplateResponse{Template: templateToProto(t)}, nil
+}
+
+func (s *templateServer) UpdateTemplate(
+	ctx context.Context,
+	req *greetpb.UpdateTemplateRequest,
+) (*greetpb.UpdateTemplateResponse, error) {
+	fmt.Printf("UpdateTemplate function was invoked with %v\n", req)
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	t := templateFromProto(req.GetTemplate())
+	if _, err := parseGreetingTemplate(t); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid template: %v", err)
+	}
+	if err := s.store.Update(ctx, t); err != nil {
+		return nil, templateStatus(err)
+	}
+	return &greetpb.UpdateTemplateResponse{Template: templateToProto(t)}, nil
+}
+
+func (s *templateServer) DeleteTemplate(
+	ctx context.Context,
+	req *greetpb.DeleteTemplateRequest,
+) (*greetpb.DeleteTemplateResponse, error) {
+	fmt.Printf("DeleteTemplate function was invoked with %v\n", req)
+	if err := s.requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.store.Delete(ctx, req.GetName()); err != nil {
+		return nil, templateStatus(err)
+	}
+	return &greetpb.DeleteTemplateResponse{Name: req.GetName()}, nil
+}
+
+func (s *templateServer) ListTemplate(
+	req *greetpb.ListTemplateRequest,
+	stream greetpb.GreetingTemplateService_ListTemplateServer,
+) error {
+	fmt.Println("ListTemplate function was invoked")
+	templates, err := s.store.List(stream.Context())
+	if err != nil {
+		return templateStatus(err)
+	}
+	for _, t := range templates {
+		err := stream.Send(&greetpb.ListTemplateResponse{Template: templateToProto(t)})
+		if err != nil {
+			log.Printf("Erorr while sending data to client: %v\n", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func templateFromProto(t *greetpb.GreetingTemplate) *greetingTemplate {
+	return &greetingTemplate{Name: t.GetName(), Body: t.GetBody()}
+}
+
+func templateToProto(t *greetingTemplate) *greetpb.GreetingTemplate {
+	return &greetpb.GreetingTemplate{Name: t.Name, Body: t.Body}
+}
+
+func templateStatus(err error) error {
+	switch {
+	case errors.Is(err, errTemplateNotFound):
+		return status.Errorf(codes.NotFound, "Cannot find template: %v", err)
+	case errors.Is(err, errTemplateExists):
+		return status.Errorf(codes.AlreadyExists, "Cannot create template: %v", err)
+	}
+	return status.Errorf(codes.Internal, "Template store error: %v", err)
+}
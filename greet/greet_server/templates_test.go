@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"github.com/wiliamhw/golang-grpc-example/greet/greetpb"
+	"strings"
+	"testing"
+)
+
+func TestParseGreetingTemplate(t *testing.T) {
+	valid := []string{
+		"Hello {{.FirstName}}",
+		"{{if .Honorific}}{{.Honorific}} {{end}}{{.LastName}}",
+		"{{with .Locale}}[{{.}}]{{end}} {{.FirstName}}",
+		`{{if and (eq .Locale "en") (gt (len .FirstName) 3)}}long{{else}}short{{end}}`,
+		"{{/* a comment */}}{{if not .LastName}}{{.FirstName}}{{end}}",
+	}
+	for _, body := range valid {
+		if _, err := parseGreetingTemplate(&greetingTemplate{Name: "valid", Body: body}); err != nil {
+			t.Errorf("parseGreetingTemplate(%q) failed: %v", body, err)
+		}
+	}
+
+	invalid := []struct {
+		body string
+		err  string
+	}{
+		{"{{range .FirstName}}x{{end}}", "is not allowed"},
+		{`{{template "x"}}`, "is not allowed"},
+		{`{{define "x"}}a{{end}}b`, "define and block"},
+		{`{{block "x" .}}a{{end}}`, "define and block"},
+		{"{{$name := .FirstName}}{{$name}}", "variables"},
+		{"{{if $x := .FirstName}}{{end}}", "variables"},
+		{`{{printf "%s" .FirstName}}`, `function "printf"`},
+		{"{{.FirstName | html}}", `function "html"`},
+		{"{{.FirstName.Foo}}", "unknown field"},
+		{"{{.Email}}", "unknown field"},
+		// Branches that the sample data does not take are checked too
+		{"{{if .Locale}}{{else}}{{.Email}}{{end}}", "unknown field"},
+		{"{{.FirstName", "unclosed action"},
+		{"", "body must have"},
+		{strings.Repeat("x", maxTemplateBodyLength+1), "body must have"},
+	}
+	for _, tt := range invalid {
+		_, err := parseGreetingTemplate(&greetingTemplate{Name: "invalid", Body: tt.body})
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("parseGreetingTemplate(%.40q) = %v, want an error containing %q", tt.body, err, tt.err)
+		}
+	}
+
+	for _, name := range []string{"", "Upper", "-dash", "with space", strings.Repeat("a", 65)} {
+		if _, err := parseGreetingTemplate(&greetingTemplate{Name: name, Body: "x"}); err == nil {
+			t.Errorf("parseGreetingTemplate() named %q succeeded", name)
+		}
+	}
+}
+
+func TestRenderTemplateLimitsOutput(t *testing.T) {
+	// Small enough to save, but long names make the output grow past the limit
+	body := strings.Repeat("{{.FirstName}}", 50)
+	tmpl, err := parseGreetingTemplate(&greetingTemplate{Name: "long", Body: body})
+	if err != nil {
+		t.Fatalf("parseGreetingTemplate() failed: %v", err)
+	}
+	data := templateData{FirstName: strings.Repeat("x", 100)}
+	if _, err := renderTemplate(context.Background(), tmpl, data); err == nil || !strings.Contains(err.Error(), errTemplateOutputTooLarge.Error()) {
+		t.Errorf("renderTemplate() = %v, want %v", err, errTemplateOutputTooLarge)
+	}
+}
+
+func TestTemplateCacheFollowsVersions(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryTemplateStore()
+	cache := newTemplateCache(store)
+	greeting := &greetpb.Greeting{FirstName: "Ada", LastName: "Lovelace"}
+
+	if _, err := cache.greet(ctx, "formal", greeting); err == nil {
+		t.Errorf("greet() with a missing template succeeded")
+	}
+	if err := store.Create(ctx, &greetingTemplate{Name: "formal", Body: "Dear {{.FirstName}}"}); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if got, err := cache.greet(ctx, "formal", greeting); err != nil || got != "Dear Ada" {
+		t.Errorf("greet() = %q, %v, want %q", got, err, "Dear Ada")
+	}
+	if err := store.Update(ctx, &greetingTemplate{Name: "formal", Body: "Dear {{.LastName}}"}); err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if got, err := cache.greet(ctx, "formal", greeting); err != nil || got != "Dear Lovelace" {
+		t.Errorf("greet() after an update = %q, %v, want %q", got, err, "Dear Lovelace")
+	}
+	if err := store.Delete(ctx, "formal"); err != nil {
+		t.Fatalf("Delete() failed: %v", err)
+	}
+	if _, err := cache.greet(ctx, "formal", greeting); err == nil {
+		t.Errorf("greet() after a delete succeeded")
+	}
+}
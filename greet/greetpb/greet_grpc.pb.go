@@ -23,14 +23,34 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GreetServiceClient interface {
 	// Unary
+	// Returns NOT_FOUND if the template does not exist.
 	Greet(ctx context.Context, in *GreetRequest, opts ...grpc.CallOption) (*GreetResponse, error)
 	// Server Streaming
+	// Returns INVALID_ARGUMENT if the count or interval is out of range.
 	GreetManyTimes(ctx context.Context, in *GreetManyTimesRequest, opts ...grpc.CallOption) (GreetService_GreetManyTimesClient, error)
 	// Client Streaming
+	// Names are counted by first and last name. Returns RESOURCE_EXHAUSTED past
+	// 1000 messages or 64 KiB of requests.
 	LongGreet(ctx context.Context, opts ...grpc.CallOption) (GreetService_LongGreetClient, error)
+	// Same as LongGreet, acknowledging every greeting as it is received.
+	LongGreetWithAcks(ctx context.Context, opts ...grpc.CallOption) (GreetService_LongGreetWithAcksClient, error)
 	// Bi Directional (BiDi) Streaming
 	GreetEveryone(ctx context.Context, opts ...grpc.CallOption) (GreetService_GreetEveryoneClient, error)
+	// Broadcasts every message and join or leave event to all the members of a room.
+	// Returns INVALID_ARGUMENT if the first message has no room or name, and
+	// RESOURCE_EXHAUSTED when a slow subscriber is disconnected.
+	Chat(ctx context.Context, opts ...grpc.CallOption) (GreetService_ChatClient, error)
+	// Presence
+	// Clients are keyed by their greeting's first and last name and their address. Dead
+	// peers are detected with keepalive pings and go offline.
+	ListPresence(ctx context.Context, in *ListPresenceRequest, opts ...grpc.CallOption) (*ListPresenceResponse, error)
+	// Starts with a snapshot of the clients online, then streams every change.
+	// Returns RESOURCE_EXHAUSTED if the watcher does not keep up.
+	WatchPresence(ctx context.Context, in *WatchPresenceRequest, opts ...grpc.CallOption) (GreetService_WatchPresenceClient, error)
 	// Unary With Deadline
+	// Returns DEADLINE_EXCEEDED or CANCELLED as soon as the work is interrupted. The
+	// deadline-budget-ms and deadline-remaining-ms trailers report the time left
+	// when the request arrived and when it finished, if the client set a deadline.
 	GreetWithDeadline(ctx context.Context, in *GreetWithDeadlineRequest, opts ...grpc.CallOption) (*GreetWithDeadlineResponse, error)
 }
 
@@ -117,8 +137,39 @@ func (x *greetServiceLongGreetClient) CloseAndRecv() (*LongGreetResponse, error)
 	return m, nil
 }
 
+func (c *greetServiceClient) LongGreetWithAcks(ctx context.Context, opts ...grpc.CallOption) (GreetService_LongGreetWithAcksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GreetService_ServiceDesc.Streams[2], "/greet.GreetService/LongGreetWithAcks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &greetServiceLongGreetWithAcksClient{stream}
+	return x, nil
+}
+
+type GreetService_LongGreetWithAcksClient interface {
+	Send(*LongGreetRequest) error
+	Recv() (*LongGreetEvent, error)
+	grpc.ClientStream
+}
+
+type greetServiceLongGreetWithAcksClient struct {
+	grpc.ClientStream
+}
+
+func (x *greetServiceLongGreetWithAcksClient) Send(m *LongGreetRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *greetServiceLongGreetWithAcksClient) Recv() (*LongGreetEvent, error) {
+	m := new(LongGreetEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *greetServiceClient) GreetEveryone(ctx context.Context, opts ...grpc.CallOption) (GreetService_GreetEveryoneClient, error) {
-	stream, err := c.cc.NewStream(ctx, &GreetService_ServiceDesc.Streams[2], "/greet.GreetService/GreetEveryone", opts...)
+	stream, err := c.cc.NewStream(ctx, &GreetService_ServiceDesc.Streams[3], "/greet.GreetService/GreetEveryone", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -148,6 +199,78 @@ func (x *greetServiceGreetEveryoneClient) Recv() (*GreetEveryoneResponse, error)
 	return m, nil
 }
 
+func (c *greetServiceClient) Chat(ctx context.Context, opts ...grpc.CallOption) (GreetService_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GreetService_ServiceDesc.Streams[4], "/greet.GreetService/Chat", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &greetServiceChatClient{stream}
+	return x, nil
+}
+
+type GreetService_ChatClient interface {
+	Send(*ChatRequest) error
+	Recv() (*ChatEvent, error)
+	grpc.ClientStream
+}
+
+type greetServiceChatClient struct {
+	grpc.ClientStream
+}
+
+func (x *greetServiceChatClient) Send(m *ChatRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *greetServiceChatClient) Recv() (*ChatEvent, error) {
+	m := new(ChatEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *greetServiceClient) ListPresence(ctx context.Context, in *ListPresenceRequest, opts ...grpc.CallOption) (*ListPresenceResponse, error) {
+	out := new(ListPresenceResponse)
+	err := c.cc.Invoke(ctx, "/greet.GreetService/ListPresence", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greetServiceClient) WatchPresence(ctx context.Context, in *WatchPresenceRequest, opts ...grpc.CallOption) (GreetService_WatchPresenceClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GreetService_ServiceDesc.Streams[5], "/greet.GreetService/WatchPresence", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &greetServiceWatchPresenceClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GreetService_WatchPresenceClient interface {
+	Recv() (*PresenceEvent, error)
+	grpc.ClientStream
+}
+
+type greetServiceWatchPresenceClient struct {
+	grpc.ClientStream
+}
+
+func (x *greetServiceWatchPresenceClient) Recv() (*PresenceEvent, error) {
+	m := new(PresenceEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *greetServiceClient) GreetWithDeadline(ctx context.Context, in *GreetWithDeadlineRequest, opts ...grpc.CallOption) (*GreetWithDeadlineResponse, error) {
 	out := new(GreetWithDeadlineResponse)
 	err := c.cc.Invoke(ctx, "/greet.GreetService/GreetWithDeadline", in, out, opts...)
@@ -162,14 +285,34 @@ func (c *greetServiceClient) GreetWithDeadline(ctx context.Context, in *GreetWit
 // for forward compatibility
 type GreetServiceServer interface {
 	// Unary
+	// Returns NOT_FOUND if the template does not exist.
 	Greet(context.Context, *GreetRequest) (*GreetResponse, error)
 	// Server Streaming
+	// Returns INVALID_ARGUMENT if the count or interval is out of range.
 	GreetManyTimes(*GreetManyTimesRequest, GreetService_GreetManyTimesServer) error
 	// Client Streaming
+	// Names are counted by first and last name. Returns RESOURCE_EXHAUSTED past
+	// 1000 messages or 64 KiB of requests.
 	LongGreet(GreetService_LongGreetServer) error
+	// Same as LongGreet, acknowledging every greeting as it is received.
+	LongGreetWithAcks(GreetService_LongGreetWithAcksServer) error
 	// Bi Directional (BiDi) Streaming
 	GreetEveryone(GreetService_GreetEveryoneServer) error
+	// Broadcasts every message and join or leave event to all the members of a room.
+	// Returns INVALID_ARGUMENT if the first message has no room or name, and
+	// RESOURCE_EXHAUSTED when a slow subscriber is disconnected.
+	Chat(GreetService_ChatServer) error
+	// Presence
+	// Clients are keyed by their greeting's first and last name and their address. Dead
+	// peers are detected with keepalive pings and go offline.
+	ListPresence(context.Context, *ListPresenceRequest) (*ListPresenceResponse, error)
+	// Starts with a snapshot of the clients online, then streams every change.
+	// Returns RESOURCE_EXHAUSTED if the watcher does not keep up.
+	WatchPresence(*WatchPresenceRequest, GreetService_WatchPresenceServer) error
 	// Unary With Deadline
+	// Returns DEADLINE_EXCEEDED or CANCELLED as soon as the work is interrupted. The
+	// deadline-budget-ms and deadline-remaining-ms trailers report the time left
+	// when the request arrived and when it finished, if the client set a deadline.
 	GreetWithDeadline(context.Context, *GreetWithDeadlineRequest) (*GreetWithDeadlineResponse, error)
 	mustEmbedUnimplementedGreetServiceServer()
 }
@@ -187,9 +330,21 @@ func (UnimplementedGreetServiceServer) GreetManyTimes(*GreetManyTimesRequest, Gr
 func (UnimplementedGreetServiceServer) LongGreet(GreetService_LongGreetServer) error {
 	return status.Errorf(codes.Unimplemented, "method LongGreet not implemented")
 }
+func (UnimplementedGreetServiceServer) LongGreetWithAcks(GreetService_LongGreetWithAcksServer) error {
+	return status.Errorf(codes.Unimplemented, "method LongGreetWithAcks not implemented")
+}
 func (UnimplementedGreetServiceServer) GreetEveryone(GreetService_GreetEveryoneServer) error {
 	return status.Errorf(codes.Unimplemented, "method GreetEveryone not implemented")
 }
+func (UnimplementedGreetServiceServer) Chat(GreetService_ChatServer) error {
+	return status.Errorf(codes.Unimplemented, "method Chat not implemented")
+}
+func (UnimplementedGreetServiceServer) ListPresence(context.Context, *ListPresenceRequest) (*ListPresenceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPresence not implemented")
+}
+func (UnimplementedGreetServiceServer) WatchPresence(*WatchPresenceRequest, GreetService_WatchPresenceServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPresence not implemented")
+}
 func (UnimplementedGreetServiceServer) GreetWithDeadline(context.Context, *GreetWithDeadlineRequest) (*GreetWithDeadlineResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GreetWithDeadline not implemented")
 }
@@ -271,6 +426,32 @@ func (x *greetServiceLongGreetServer) Recv() (*LongGreetRequest, error) {
 	return m, nil
 }
 
+func _GreetService_LongGreetWithAcks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GreetServiceServer).LongGreetWithAcks(&greetServiceLongGreetWithAcksServer{stream})
+}
+
+type GreetService_LongGreetWithAcksServer interface {
+	Send(*LongGreetEvent) error
+	Recv() (*LongGreetRequest, error)
+	grpc.ServerStream
+}
+
+type greetServiceLongGreetWithAcksServer struct {
+	grpc.ServerStream
+}
+
+func (x *greetServiceLongGreetWithAcksServer) Send(m *LongGreetEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *greetServiceLongGreetWithAcksServer) Recv() (*LongGreetRequest, error) {
+	m := new(LongGreetRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _GreetService_GreetEveryone_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(GreetServiceServer).GreetEveryone(&greetServiceGreetEveryoneServer{stream})
 }
@@ -297,6 +478,71 @@ func (x *greetServiceGreetEveryoneServer) Recv() (*GreetEveryoneRequest, error)
 	return m, nil
 }
 
+func _GreetService_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GreetServiceServer).Chat(&greetServiceChatServer{stream})
+}
+
+type GreetService_ChatServer interface {
+	Send(*ChatEvent) error
+	Recv() (*ChatRequest, error)
+	grpc.ServerStream
+}
+
+type greetServiceChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *greetServiceChatServer) Send(m *ChatEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *greetServiceChatServer) Recv() (*ChatRequest, error) {
+	m := new(ChatRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _GreetService_ListPresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreetServiceServer).ListPresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/greet.GreetService/ListPresence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreetServiceServer).ListPresence(ctx, req.(*ListPresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GreetService_WatchPresence_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPresenceRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GreetServiceServer).WatchPresence(m, &greetServiceWatchPresenceServer{stream})
+}
+
+type GreetService_WatchPresenceServer interface {
+	Send(*PresenceEvent) error
+	grpc.ServerStream
+}
+
+type greetServiceWatchPresenceServer struct {
+	grpc.ServerStream
+}
+
+func (x *greetServiceWatchPresenceServer) Send(m *PresenceEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _GreetService_GreetWithDeadline_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GreetWithDeadlineRequest)
 	if err := dec(in); err != nil {
@@ -326,6 +572,10 @@ var GreetService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Greet",
 			Handler:    _GreetService_Greet_Handler,
 		},
+		{
+			MethodName: "ListPresence",
+			Handler:    _GreetService_ListPresence_Handler,
+		},
 		{
 			MethodName: "GreetWithDeadline",
 			Handler:    _GreetService_GreetWithDeadline_Handler,
@@ -342,12 +592,296 @@ var GreetService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _GreetService_LongGreet_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "LongGreetWithAcks",
+			Handler:       _GreetService_LongGreetWithAcks_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "GreetEveryone",
 			Handler:       _GreetService_GreetEveryone_Handler,
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "Chat",
+			Handler:       _GreetService_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchPresence",
+			Handler:       _GreetService_WatchPresence_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "greet/greetpb/greet.proto",
+}
+
+// GreetingTemplateServiceClient is the client API for GreetingTemplateService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GreetingTemplateServiceClient interface {
+	// Mutations require an "authorization: Bearer <token>" metadata header matching the admin
+	// token of the server. They return UNAUTHENTICATED without the header, PERMISSION_DENIED
+	// with a wrong token, and FAILED_PRECONDITION when the server has no admin token.
+	// Templates that do not parse or render return INVALID_ARGUMENT.
+	CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*CreateTemplateResponse, error)
+	ReadTemplate(ctx context.Context, in *ReadTemplateRequest, opts ...grpc.CallOption) (*ReadTemplateResponse, error)
+	UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*UpdateTemplateResponse, error)
+	DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error)
+	ListTemplate(ctx context.Context, in *ListTemplateRequest, opts ...grpc.CallOption) (GreetingTemplateService_ListTemplateClient, error)
+}
+
+type greetingTemplateServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGreetingTemplateServiceClient(cc grpc.ClientConnInterface) GreetingTemplateServiceClient {
+	return &greetingTemplateServiceClient{cc}
+}
+
+func (c *greetingTemplateServiceClient) CreateTemplate(ctx context.Context, in *CreateTemplateRequest, opts ...grpc.CallOption) (*CreateTemplateResponse, error) {
+	out := new(CreateTemplateResponse)
+	err := c.cc.Invoke(ctx, "/greet.GreetingTemplateService/CreateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greetingTemplateServiceClient) ReadTemplate(ctx context.Context, in *ReadTemplateRequest, opts ...grpc.CallOption) (*ReadTemplateResponse, error) {
+	out := new(ReadTemplateResponse)
+	err := c.cc.Invoke(ctx, "/greet.GreetingTemplateService/ReadTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greetingTemplateServiceClient) UpdateTemplate(ctx context.Context, in *UpdateTemplateRequest, opts ...grpc.CallOption) (*UpdateTemplateResponse, error) {
+	out := new(UpdateTemplateResponse)
+	err := c.cc.Invoke(ctx, "/greet.GreetingTemplateService/UpdateTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greetingTemplateServiceClient) DeleteTemplate(ctx context.Context, in *DeleteTemplateRequest, opts ...grpc.CallOption) (*DeleteTemplateResponse, error) {
+	out := new(DeleteTemplateResponse)
+	err := c.cc.Invoke(ctx, "/greet.GreetingTemplateService/DeleteTemplate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *greetingTemplateServiceClient) ListTemplate(ctx context.Context, in *ListTemplateRequest, opts ...grpc.CallOption) (GreetingTemplateService_ListTemplateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &GreetingTemplateService_ServiceDesc.Streams[0], "/greet.GreetingTemplateService/ListTemplate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &greetingTemplateServiceListTemplateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GreetingTemplateService_ListTemplateClient interface {
+	Recv() (*ListTemplateResponse, error)
+	grpc.ClientStream
+}
+
+type greetingTemplateServiceListTemplateClient struct {
+	grpc.ClientStream
+}
+
+func (x *greetingTemplateServiceListTemplateClient) Recv() (*ListTemplateResponse, error) {
+	m := new(ListTemplateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// GreetingTemplateServiceServer is the server API for GreetingTemplateService service.
+// All implementations must embed UnimplementedGreetingTemplateServiceServer
+// for forward compatibility
+type GreetingTemplateServiceServer interface {
+	// Mutations require an "authorization: Bearer <token>" metadata header matching the admin
+	// token of the server. They return UNAUTHENTICATED without the header, PERMISSION_DENIED
+	// with a wrong token, and FAILED_PRECONDITION when the server has no admin token.
+	// Templates that do not parse or render return INVALID_ARGUMENT.
+	CreateTemplate(context.Context, *CreateTemplateRequest) (*CreateTemplateResponse, error)
+	ReadTemplate(context.Context, *ReadTemplateRequest) (*ReadTemplateResponse, error)
+	UpdateTemplate(context.Context, *UpdateTemplateRequest) (*UpdateTemplateResponse, error)
+	DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error)
+	ListTemplate(*ListTemplateRequest, GreetingTemplateService_ListTemplateServer) error
+	mustEmbedUnimplementedGreetingTemplateServiceServer()
+}
+
+// UnimplementedGreetingTemplateServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedGreetingTemplateServiceServer struct {
+}
+
+func (UnimplementedGreetingTemplateServiceServer) CreateTemplate(context.Context, *CreateTemplateRequest) (*CreateTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTemplate not implemented")
+}
+func (UnimplementedGreetingTemplateServiceServer) ReadTemplate(context.Context, *ReadTemplateRequest) (*ReadTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadTemplate not implemented")
+}
+func (UnimplementedGreetingTemplateServiceServer) UpdateTemplate(context.Context, *UpdateTemplateRequest) (*UpdateTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTemplate not implemented")
+}
+func (UnimplementedGreetingTemplateServiceServer) DeleteTemplate(context.Context, *DeleteTemplateRequest) (*DeleteTemplateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTemplate not implemented")
+}
+func (UnimplementedGreetingTemplateServiceServer) ListTemplate(*ListTemplateRequest, GreetingTemplateService_ListTemplateServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListTemplate not implemented")
+}
+func (UnimplementedGreetingTemplateServiceServer) mustEmbedUnimplementedGreetingTemplateServiceServer() {
+}
+
+// UnsafeGreetingTemplateServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GreetingTemplateServiceServer will
+// result in compilation errors.
+type UnsafeGreetingTemplateServiceServer interface {
+	mustEmbedUnimplementedGreetingTemplateServiceServer()
+}
+
+func RegisterGreetingTemplateServiceServer(s grpc.ServiceRegistrar, srv GreetingTemplateServiceServer) {
+	s.RegisterService(&GreetingTemplateService_ServiceDesc, srv)
+}
+
+func _GreetingTemplateService_CreateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreetingTemplateServiceServer).CreateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/greet.GreetingTemplateService/CreateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreetingTemplateServiceServer).CreateTemplate(ctx, req.(*CreateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GreetingTemplateService_ReadTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReadTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreetingTemplateServiceServer).ReadTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/greet.GreetingTemplateService/ReadTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreetingTemplateServiceServer).ReadTemplate(ctx, req.(*ReadTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GreetingTemplateService_UpdateTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreetingTemplateServiceServer).UpdateTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/greet.GreetingTemplateService/UpdateTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreetingTemplateServiceServer).UpdateTemplate(ctx, req.(*UpdateTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GreetingTemplateService_DeleteTemplate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTemplateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreetingTemplateServiceServer).DeleteTemplate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/greet.GreetingTemplateService/DeleteTemplate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreetingTemplateServiceServer).DeleteTemplate(ctx, req.(*DeleteTemplateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GreetingTemplateService_ListTemplate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListTemplateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GreetingTemplateServiceServer).ListTemplate(m, &greetingTemplateServiceListTemplateServer{stream})
+}
+
+type GreetingTemplateService_ListTemplateServer interface {
+	Send(*ListTemplateResponse) error
+	grpc.ServerStream
+}
+
+type greetingTemplateServiceListTemplateServer struct {
+	grpc.ServerStream
+}
+
+func (x *greetingTemplateServiceListTemplateServer) Send(m *ListTemplateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// GreetingTemplateService_ServiceDesc is the grpc.ServiceDesc for GreetingTemplateService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GreetingTemplateService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "greet.GreetingTemplateService",
+	HandlerType: (*GreetingTemplateServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateTemplate",
+			Handler:    _GreetingTemplateService_CreateTemplate_Handler,
+		},
+		{
+			MethodName: "ReadTemplate",
+			Handler:    _GreetingTemplateService_ReadTemplate_Handler,
+		},
+		{
+			MethodName: "UpdateTemplate",
+			Handler:    _GreetingTemplateService_UpdateTemplate_Handler,
+		},
+		{
+			MethodName: "DeleteTemplate",
+			Handler:    _GreetingTemplateService_DeleteTemplate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListTemplate",
+			Handler:       _GreetingTemplateService_ListTemplate_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "greet/greetpb/greet.proto",
 }
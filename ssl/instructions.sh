@@ -8,13 +8,15 @@
 # server.csr: Server certificate signing request (this should be shared with the CA owner)
 # server.crt: Server certificate signed by the CA (this would be sent back by the CA owner) - keep on server
 # server.pem: Conversion of server.key into a format gRPC likes (this shouldn't be shared)
+# client.key, client.csr, client.crt, client.pem: The same for the client certificate presented in mTLS mode
 
 # Summary
-# Private files: ca.key, server.key, server.pem, server.crt
+# Private files: ca.key, server.key, server.pem, server.crt, client.key, client.pem, client.crt
 # "Share" files: ca.crt (needed by the client), server.csr (needed by the CA)
 
 # Changes these CN's to match your hosts in your environment if needed.
 SERVER_CN=localhost
+CLIENT_CN=greet-client
 
 # Step 1: Generate Certificate Authority + Trust Certificate (ca.crt)
 openssl genrsa -passout pass:1111 -des3 -out ca.key 4096
@@ -30,4 +32,14 @@ openssl req -passin pass:1111 -new -key server.key -out server.csr -subj "/CN=${
 openssl x509 -req -passin pass:1111 -days 3650 -in server.csr -CA ca.crt -CAkey ca.key -set_serial 01 -out server.crt -extensions req_ext -extfile ssl.cnf
 
 # Step 5: Convert the server certificate to .pem format (server.pem) - usable by gRPC
-openssl pkcs8 -topk8 -nocrypt -passin pass:1111 -in server.key -out server.pem
\ No newline at end of file
+openssl pkcs8 -topk8 -nocrypt -passin pass:1111 -in server.key -out server.pem
+
+# Step 6: Generate the Client Private Key (client.key), only needed for mTLS
+openssl genrsa -passout pass:1111 -des3 -out client.key 4096
+
+# Step 7: Get a client certificate signing request and sign it with the CA - client.crt
+openssl req -passin pass:1111 -new -key client.key -out client.csr -subj "/CN=${CLIENT_CN}"
+openssl x509 -req -passin pass:1111 -days 3650 -in client.csr -CA ca.crt -CAkey ca.key -set_serial 02 -out client.crt -extensions client_ext -extfile ssl.cnf
+
+# Step 8: Convert the client key to .pem format (client.pem) - usable by gRPC
+openssl pkcs8 -topk8 -nocrypt -passin pass:1111 -in client.key -out client.pem